@@ -1,38 +1,108 @@
 package main
 
 import (
-	"archive/zip"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"aer/internal/actionlog"
+	"aer/internal/ghenv"
+	"aer/internal/github"
+	"aer/internal/installer"
+	"aer/internal/release"
+	"aer/internal/sbom"
 )
 
-func main() {
-	var repo string
-	var version string
-	var runnerOS string
-	var runnerArch string
-	var dest string
+// config holds the install command's inputs.
+type config struct {
+	Repo             string
+	Version          string
+	RunnerOS         string
+	RunnerArch       string
+	RunnerLibc       string
+	Dest             string
+	FromFile         string
+	FromDir          string
+	Lockfile         string
+	SkipVerify       bool
+	PublicKey        string
+	RequireSignature bool
+	SBOM             string
+	GitHub           *github.Client
+}
 
-	flag.StringVar(&repo, "repo", "", "repository that hosts the release assets")
-	flag.StringVar(&version, "version", "", "release tag to download")
-	flag.StringVar(&runnerOS, "runner-os", "", "runner operating system")
-	flag.StringVar(&runnerArch, "runner-arch", "", "runner architecture")
-	flag.StringVar(&dest, "dest", "", "destination directory for the aer binary")
+func main() {
+	var cfg config
+	var debug bool
+
+	flag.StringVar(&cfg.Repo, "repo", "", "repository that hosts the release assets")
+	flag.StringVar(&cfg.Version, "version", "", "release tag to download")
+	flag.StringVar(&cfg.RunnerOS, "runner-os", "", "runner operating system")
+	flag.StringVar(&cfg.RunnerArch, "runner-arch", "", "runner architecture")
+	flag.StringVar(&cfg.RunnerLibc, "runner-libc", "", "C library on Linux runners: gnu or musl (detected when empty)")
+	flag.StringVar(&cfg.Dest, "dest", "", "destination directory for the aer binary")
+	flag.StringVar(&cfg.FromFile, "from-file", "", "install from a local release archive instead of downloading")
+	flag.StringVar(&cfg.FromDir, "from-dir", "", "install from a directory of release assets including manifest-<version>.json or SHA256SUMS-<version>")
+	flag.StringVar(&cfg.Lockfile, "lockfile", "", "aer.lock whose recorded digest the archive must match")
+	flag.BoolVar(&cfg.SkipVerify, "skip-verify", false, "skip running the installed binary to confirm its version")
+	flag.StringVar(&cfg.PublicKey, "public-key", "", "minisign or PEM public key that signs SHA256SUMS-<version> (default: the pinned release key)")
+	flag.BoolVar(&cfg.RequireSignature, "require-signature", false, "refuse to install a release whose checksums are not signed")
+	flag.StringVar(&cfg.SBOM, "sbom", "", "also install the release's SBOM for the binary: cyclonedx or spdx")
+	flag.BoolVar(&debug, "debug", actionlog.RunnerDebug(), "log debug output (also enabled by RUNNER_DEBUG=1)")
 	flag.Parse()
+	actionlog.SetDebug(debug)
+
+	cfg.GitHub = github.NewClient()
+	if err := run(cfg, ghenv.FromEnvironment()); err != nil {
+		actionlog.Fatal(err)
+	}
+}
 
-	if repo == "" || version == "" {
-		log.Fatal("both --repo and --version are required")
+// run installs the aer binary into cfg.Dest, adds it to PATH and writes the
+// binary and version step outputs.
+func run(cfg config, env ghenv.Env) error {
+	if cfg.FromFile != "" && cfg.FromDir != "" {
+		return errors.New("--from-file and --from-dir are mutually exclusive")
+	}
+
+	version := cfg.Version
+	var source installer.Source
+	var archiveFile string
+	requireChecksums := false
+	switch {
+	case cfg.FromFile != "":
+		archiveFile = filepath.Base(cfg.FromFile)
+		if version == "" {
+			_, parsed, ok := release.ParseArchiveName(archiveFile)
+			if !ok {
+				return fmt.Errorf("cannot determine the version from %s; pass --version", archiveFile)
+			}
+			version = parsed
+		}
+		source = installer.DirSource{Dir: filepath.Dir(cfg.FromFile)}
+	case cfg.FromDir != "":
+		if version == "" {
+			inferred, err := release.VersionFromDir(cfg.FromDir)
+			if err != nil {
+				return err
+			}
+			version = inferred
+		}
+		source = installer.DirSource{Dir: cfg.FromDir}
+		requireChecksums = true
+	default:
+		if cfg.Repo == "" || version == "" {
+			return errors.New("both --repo and --version are required")
+		}
+		source = installer.ReleaseSource{Client: cfg.GitHub, Repo: cfg.Repo, Version: version}
 	}
 
-	runnerOS = strings.TrimSpace(runnerOS)
-	runnerArch = strings.TrimSpace(runnerArch)
+	runnerOS := strings.TrimSpace(cfg.RunnerOS)
+	runnerArch := strings.TrimSpace(cfg.RunnerArch)
 
 	if runnerOS == "" {
 		runnerOS = runtime.GOOS
@@ -41,228 +111,161 @@ func main() {
 		runnerArch = runtime.GOARCH
 	}
 
-	platformKey, err := normalizeOS(runnerOS)
+	libc, err := installer.NormalizeLibc(strings.TrimSpace(cfg.RunnerLibc))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-
-	cpuKey, err := normalizeArch(platformKey, runnerArch)
-	if err != nil {
-		log.Fatal(err)
+	if libc == "" && strings.EqualFold(runnerOS, runtime.GOOS) {
+		libc = installer.DetectLibc()
 	}
 
-	var archiveName string
-	var binaryName string
-	if platformKey == "windows" {
-		archiveName = fmt.Sprintf("aer_windows_amd64_%s.zip", version)
-		binaryName = "aer.exe"
-	} else {
-		archiveName = fmt.Sprintf("aer_%s_%s_%s.zip", platformKey, cpuKey, version)
-		binaryName = "aer"
+	var locked release.Checksums
+	if cfg.Lockfile != "" {
+		lock, err := release.ReadLock(cfg.Lockfile)
+		if err != nil {
+			return fmt.Errorf("read lockfile: %w", err)
+		}
+		if lock.Version != version {
+			return fmt.Errorf("%s pins %s but %s was requested", cfg.Lockfile, lock.Version, version)
+		}
+		locked = lock.Checksums()
 	}
 
-	if dest == "" {
-		log.Fatal("--dest must point to a writable directory (e.g. $RUNNER_TEMP/aer)")
-	}
-	if err := os.MkdirAll(dest, 0o755); err != nil {
-		log.Fatalf("create dest directory: %v", err)
+	sbomExt := ""
+	if cfg.SBOM != "" {
+		if sbomExt, err = sbom.Extension(cfg.SBOM); err != nil {
+			return fmt.Errorf("--sbom: %w", err)
+		}
 	}
 
-	url := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, version, archiveName)
-	tmpDir, err := os.MkdirTemp("", "aer-action-*")
+	publicKey, err := loadPublicKey(cfg.PublicKey)
 	if err != nil {
-		log.Fatalf("create temp directory: %v", err)
+		return fmt.Errorf("load public key: %w", err)
 	}
-	defer os.RemoveAll(tmpDir)
 
-	archivePath := filepath.Join(tmpDir, archiveName)
-	if err := downloadFile(url, archivePath); err != nil {
-		log.Fatalf("download archive: %v", err)
-	}
-
-	binaryPath, err := extractBinary(archivePath, binaryName, tmpDir)
+	candidates, err := installer.ResolveTargets(runnerOS, runnerArch, libc)
 	if err != nil {
-		log.Fatalf("extract binary: %v", err)
+		return err
 	}
-
-	finalPath := filepath.Join(dest, binaryName)
-	if err := moveFile(binaryPath, finalPath); err != nil {
-		log.Fatalf("move binary: %v", err)
+	for _, c := range candidates {
+		actionlog.Debugf("candidate target %s (emulation: %q)", c.Target, c.Emulation)
 	}
-
-	if platformKey != "windows" {
-		if err := os.Chmod(finalPath, 0o755); err != nil {
-			log.Fatalf("chmod binary: %v", err)
+	if archiveFile != "" {
+		candidates, err = matchArchive(candidates, archiveFile, version)
+		if err != nil {
+			return err
 		}
 	}
 
-	pathFile := os.Getenv("GITHUB_PATH")
-	if pathFile == "" {
-		log.Fatal("GITHUB_PATH is not set")
+	if cfg.Dest == "" {
+		return errors.New("--dest must point to a writable directory (e.g. $RUNNER_TEMP/aer)")
 	}
-	if err := appendLine(pathFile, dest); err != nil {
-		log.Fatalf("update GITHUB_PATH: %v", err)
+	if err := os.MkdirAll(cfg.Dest, 0o755); err != nil {
+		return fmt.Errorf("create dest directory: %w", err)
 	}
 
-	outputFile := os.Getenv("GITHUB_OUTPUT")
-	if outputFile == "" {
-		log.Fatal("GITHUB_OUTPUT is not set")
-	}
-	if err := appendLine(outputFile, fmt.Sprintf("binary=%s", finalPath)); err != nil {
-		log.Fatalf("write GITHUB_OUTPUT: %v", err)
-	}
-
-	fmt.Printf("Installed aer binary to %s\n", finalPath)
-}
-
-func normalizeOS(osName string) (string, error) {
-	switch strings.ToLower(osName) {
-	case "linux":
-		return "linux", nil
-	case "macos", "darwin":
-		return "darwin", nil
-	case "windows":
-		return "windows", nil
-	default:
-		return "", fmt.Errorf("unsupported operating system: %q", osName)
-	}
-}
-
-func normalizeArch(platformKey, arch string) (string, error) {
-	switch strings.ToLower(arch) {
-	case "amd64", "x86_64", "x64":
-		return "amd64", nil
-	case "arm64", "aarch64":
-		if platformKey == "windows" {
-			return "", fmt.Errorf("windows arm64 is not supported by published AER builds")
-		}
-		return "arm64", nil
-	default:
-		return "", fmt.Errorf("unsupported architecture: %q", arch)
+	tmpDir, err := os.MkdirTemp("", "aer-action-*")
+	if err != nil {
+		return fmt.Errorf("create temp directory: %w", err)
 	}
-}
+	defer os.RemoveAll(tmpDir)
 
-func downloadFile(url, dest string) error {
-	resp, err := http.Get(url)
+	endGroup := actionlog.Group(fmt.Sprintf("Download aer %s from %s", version, source))
+	req := installer.Request{
+		Source:           source,
+		Version:          version,
+		Candidates:       candidates,
+		TmpDir:           tmpDir,
+		RequireChecksums: requireChecksums,
+		PublicKey:        publicKey,
+		RequireSignature: cfg.RequireSignature,
+		Locked:           locked,
+		Lockfile:         cfg.Lockfile,
+		Log:              actionlog.Default(),
+	}
+	fetched, err := installer.Fetch(req)
+	sbomPath := ""
+	if err == nil && cfg.SBOM != "" {
+		sbomPath, err = installer.FetchSBOM(req, fetched, cfg.SBOM, filepath.Join(cfg.Dest, "aer"+sbomExt))
+	}
+	endGroup()
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("unexpected HTTP status %s", resp.Status)
-	}
 
-	out, err := os.Create(dest)
+	selected := fetched.Candidate
+	binaryName := selected.Binary
+	binaryPath, err := installer.ExtractBinary(fetched.Path, binaryName, tmpDir)
 	if err != nil {
-		return err
+		return fmt.Errorf("extract binary: %w", err)
 	}
-	defer out.Close()
 
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		return err
+	finalPath := filepath.Join(cfg.Dest, binaryName)
+	if err := installer.MoveFile(binaryPath, finalPath); err != nil {
+		return fmt.Errorf("move binary: %w", err)
 	}
-	return nil
-}
 
-func extractBinary(archivePath, binaryName, destDir string) (string, error) {
-	reader, err := zip.OpenReader(archivePath)
-	if err != nil {
-		return "", err
+	if selected.OS != "windows" {
+		if err := os.Chmod(finalPath, 0o755); err != nil {
+			return fmt.Errorf("chmod binary: %w", err)
+		}
 	}
-	defer reader.Close()
 
-	for _, file := range reader.File {
-		if file.FileInfo().IsDir() {
-			continue
-		}
-		name := strings.TrimSuffix(file.Name, "/")
-		if strings.HasSuffix(name, binaryName) {
-			extracted := filepath.Join(destDir, binaryName)
-			if err := extractZipFile(file, extracted); err != nil {
-				return "", err
-			}
-			return extracted, nil
+	verified := version
+	if cfg.SkipVerify {
+		actionlog.Infof("Skipping post-install verification")
+	} else {
+		reported, err := installer.VerifyBinary(finalPath, version)
+		if err != nil {
+			return fmt.Errorf("verify installed binary: %w", err)
 		}
+		verified = reported
+		actionlog.Infof("Verified %s reports aer version %s", finalPath, reported)
 	}
-	return "", fmt.Errorf("%s not found in archive", binaryName)
-}
 
-func extractZipFile(file *zip.File, dest string) error {
-	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
-		return err
+	if err := env.AddPath(cfg.Dest); err != nil {
+		return fmt.Errorf("update PATH: %w", err)
 	}
-
-	rc, err := file.Open()
-	if err != nil {
-		return err
+	if err := env.SetOutput("binary", finalPath); err != nil {
+		return fmt.Errorf("write outputs: %w", err)
 	}
-	defer rc.Close()
-
-	out, err := os.Create(dest)
-	if err != nil {
-		return err
+	if err := env.SetOutput("version", verified); err != nil {
+		return fmt.Errorf("write outputs: %w", err)
 	}
-	defer out.Close()
-
-	if _, err := io.Copy(out, rc); err != nil {
-		return err
+	if err := env.SetOutput("sbom", sbomPath); err != nil {
+		return fmt.Errorf("write outputs: %w", err)
 	}
 
-	if mode := file.Mode(); mode != 0 {
-		if err := out.Chmod(mode); err != nil {
-			return err
-		}
-	}
+	actionlog.Infof("Installed aer binary to %s", finalPath)
 	return nil
 }
 
-func moveFile(src, dest string) error {
-	if err := os.RemoveAll(dest); err != nil {
-		return err
-	}
-	if err := os.Rename(src, dest); err == nil {
-		return nil
-	}
-	// Fall back to copy if rename fails (e.g. cross-device move).
-	if err := copyFile(src, dest); err != nil {
-		return err
-	}
-	return os.Remove(src)
-}
-
-func copyFile(src, dest string) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
+// releaseKey returns the key release checksums are checked against when
+// --public-key is not given. Tests replace it with a key they can sign with.
+var releaseKey = release.ReleaseKey
 
-	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
-		return err
+// loadPublicKey reads the key that signs release checksums from path,
+// falling back to the release key pinned in the action.
+func loadPublicKey(path string) (release.PublicKey, error) {
+	if path == "" {
+		return releaseKey(), nil
 	}
-
-	out, err := os.Create(dest)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer out.Close()
-
-	if _, err := io.Copy(out, in); err != nil {
-		return err
-	}
-	return nil
+	return release.ParsePublicKey(data)
 }
 
-func appendLine(path, line string) error {
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	if _, err := fmt.Fprintf(file, "%s\n", line); err != nil {
-		return err
+// matchArchive restricts candidates to the one a local archive was built
+// for, so a wrong-platform archive is rejected before extraction.
+func matchArchive(candidates []installer.Candidate, archiveFile, version string) ([]installer.Candidate, error) {
+	var expected []string
+	for _, c := range candidates {
+		if c.ArchiveName(version) == archiveFile {
+			return []installer.Candidate{c}, nil
+		}
+		expected = append(expected, c.ArchiveName(version))
 	}
-	return nil
+	return nil, fmt.Errorf("%s does not match this runner; expected %s", archiveFile, strings.Join(expected, " or "))
 }
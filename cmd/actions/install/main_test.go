@@ -0,0 +1,465 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"aer/internal/fakegithub"
+	"aer/internal/ghenv"
+	"aer/internal/installer"
+	"aer/internal/release"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const testRepo = "octoberswimmer/aer-dist"
+
+// fakeBinary is an aer stand-in that passes post-install verification.
+var fakeBinary = fakegithub.ZipFile{Name: "aer", Body: "#!/bin/sh\necho \"aer version v1.0.0\"\n", Mode: 0o755}
+
+func hostArchive(t *testing.T) string {
+	t.Helper()
+	candidates, err := installer.ResolveTargets(runtime.GOOS, runtime.GOARCH, "")
+	if err != nil {
+		t.Skipf("no aer build for the test host: %v", err)
+	}
+	return candidates[0].ArchiveName("v1.0.0")
+}
+
+// withChecksums returns a release with the given archives and a matching
+// SHA256SUMS manifest.
+func withChecksums(archives map[string][]byte) map[string][]byte {
+	assets := map[string][]byte{"SHA256SUMS-v1.0.0": fakegithub.Checksums(archives)}
+	for name, data := range archives {
+		assets[name] = data
+	}
+	return assets
+}
+
+// withManifest returns a release with the given archives and a matching
+// manifest-v1.0.0.json, but no SHA256SUMS file.
+func withManifest(t *testing.T, archives map[string][]byte) map[string][]byte {
+	assets := map[string][]byte{"manifest-v1.0.0.json": fakegithub.Manifest(t, "v1.0.0", archives)}
+	for name, data := range archives {
+		assets[name] = data
+	}
+	return assets
+}
+
+// withEditedManifest is withManifest with edit applied to each manifest
+// asset, standing in for a tampered manifest.
+func withEditedManifest(t *testing.T, archives map[string][]byte, edit func(*release.ManifestAsset)) map[string][]byte {
+	assets := withManifest(t, archives)
+	var manifest release.Manifest
+	if err := json.Unmarshal(assets["manifest-v1.0.0.json"], &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	for i := range manifest.Assets {
+		edit(&manifest.Assets[i])
+	}
+	data, err := manifest.JSON()
+	if err != nil {
+		t.Fatalf("encode manifest: %v", err)
+	}
+	assets["manifest-v1.0.0.json"] = data
+	return assets
+}
+
+func TestRunAgainstFakeGitHub(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aer uses a shell script")
+	}
+	archive := hostArchive(t)
+	platform, _, _ := release.ParseArchiveName(archive)
+	cdx := release.SBOMName(platform, "v1.0.0", ".cdx.json")
+
+	cases := []struct {
+		name    string
+		assets  func(t *testing.T) map[string][]byte
+		setup   func(s *fakegithub.Server)
+		config  func(cfg *config)
+		wantErr string
+		check   func(t *testing.T, cfg config, env *ghenv.Memory)
+	}{
+		{
+			name: "downloads and verifies the host archive",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+			},
+		},
+		{
+			name: "follows asset redirects",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+			},
+			setup: func(s *fakegithub.Server) { s.RedirectAssets() },
+		},
+		{
+			name: "makes binaries without zip permissions executable",
+			assets: func(t *testing.T) map[string][]byte {
+				bare := fakeBinary
+				bare.Mode = 0
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, bare)})
+			},
+			check: func(t *testing.T, cfg config, env *ghenv.Memory) {
+				info, err := os.Stat(filepath.Join(cfg.Dest, "aer"))
+				if err != nil || info.Mode().Perm() != 0o755 {
+					t.Fatalf("expected an executable binary, got %v (%v)", info.Mode(), err)
+				}
+			},
+		},
+		{
+			name: "installs without checksums",
+			assets: func(t *testing.T) map[string][]byte {
+				return map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)}
+			},
+		},
+		{
+			name: "verifies against the release manifest",
+			assets: func(t *testing.T) map[string][]byte {
+				return withManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+			},
+		},
+		{
+			name: "rejects a digest mismatch against the manifest",
+			assets: func(t *testing.T) map[string][]byte {
+				assets := withManifest(t, map[string][]byte{archive: []byte("published")})
+				assets[archive] = fakegithub.Zip(t, fakeBinary)
+				return assets
+			},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name: "prefers the manifest over the checksums file",
+			assets: func(t *testing.T) map[string][]byte {
+				assets := withManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+				assets["SHA256SUMS-v1.0.0"] = []byte("not a checksums file")
+				return assets
+			},
+		},
+		{
+			name: "rejects a manifest binary outside the install directory",
+			assets: func(t *testing.T) map[string][]byte {
+				return withEditedManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)}, func(a *release.ManifestAsset) {
+					a.Binary = "../../bin/aer"
+				})
+			},
+			wantErr: `binary name "../../bin/aer", which is not a plain file name`,
+		},
+		{
+			name: "rejects a manifest archive name with a path",
+			assets: func(t *testing.T) map[string][]byte {
+				return withEditedManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)}, func(a *release.ManifestAsset) {
+					a.Name = `..\` + a.Name
+				})
+			},
+			wantErr: "which is not a plain file name",
+		},
+		{
+			name: "rejects a manifest archive named for another platform",
+			assets: func(t *testing.T) map[string][]byte {
+				return withEditedManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)}, func(a *release.ManifestAsset) {
+					a.Platform = "plan9_amd64"
+				})
+			},
+			wantErr: "as the plan9_amd64 archive for v1.0.0",
+		},
+		{
+			name: "rejects a manifest archive named for another version",
+			assets: func(t *testing.T) map[string][]byte {
+				return withEditedManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)}, func(a *release.ManifestAsset) {
+					a.Name = strings.Replace(a.Name, "v1.0.0", "v0.9.0", 1)
+				})
+			},
+			wantErr: "archive for v1.0.0",
+		},
+		{
+			name: "rejects a manifest SBOM name with a path",
+			assets: func(t *testing.T) map[string][]byte {
+				return withEditedManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary), cdx: []byte("{}\n")}, func(a *release.ManifestAsset) {
+					a.SBOMs[0].Name = "/etc/aer.cdx.json"
+				})
+			},
+			wantErr: `SBOM name "/etc/aer.cdx.json"`,
+		},
+		{
+			name: "installs the SBOM next to the binary",
+			assets: func(t *testing.T) map[string][]byte {
+				return withManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary), cdx: []byte("{\"bomFormat\":\"CycloneDX\"}\n")})
+			},
+			config: func(cfg *config) { cfg.SBOM = "cyclonedx" },
+			check: func(t *testing.T, cfg config, env *ghenv.Memory) {
+				path := filepath.Join(cfg.Dest, "aer.cdx.json")
+				if env.Outputs["sbom"] != path {
+					t.Fatalf("expected sbom output %s, got %v", path, env.Outputs)
+				}
+				if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), "CycloneDX") {
+					t.Fatalf("SBOM not installed: %v", err)
+				}
+			},
+		},
+		{
+			name: "rejects an SBOM that does not match the manifest",
+			assets: func(t *testing.T) map[string][]byte {
+				assets := withManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary), cdx: []byte("{}\n")})
+				assets[cdx] = []byte("tampered")
+				return assets
+			},
+			config:  func(cfg *config) { cfg.SBOM = "cyclonedx" },
+			wantErr: "verify SBOM: checksum mismatch",
+		},
+		{
+			name: "installs without an SBOM the release does not publish",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+			},
+			config: func(cfg *config) { cfg.SBOM = "spdx" },
+			check: func(t *testing.T, cfg config, env *ghenv.Memory) {
+				if path, ok := env.Outputs["sbom"]; !ok || path != "" {
+					t.Fatalf("expected an empty sbom output, got %v", env.Outputs)
+				}
+			},
+		},
+		{
+			name: "rejects an unknown SBOM format",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+			},
+			config:  func(cfg *config) { cfg.SBOM = "swid" },
+			wantErr: `--sbom: unknown SBOM format "swid"`,
+		},
+		{
+			name: "skips builds the manifest does not list",
+			assets: func(t *testing.T) map[string][]byte {
+				return withManifest(t, map[string][]byte{"aer_darwin_amd64_v1.0.0.zip": fakegithub.Zip(t, fakeBinary)})
+			},
+			config: func(cfg *config) {
+				cfg.RunnerOS, cfg.RunnerArch, cfg.SkipVerify = "macOS", "ARM64", true
+			},
+		},
+		{
+			name: "reports a runner the manifest has no build for",
+			assets: func(t *testing.T) map[string][]byte {
+				return withManifest(t, map[string][]byte{"aer_windows_amd64_v1.0.0.zip": []byte("zip")})
+			},
+			config:  func(cfg *config) { cfg.RunnerOS, cfg.RunnerArch = "Linux", "X64" },
+			wantErr: "manifest-v1.0.0.json has no build for this runner (linux/amd64 (gnu)); it publishes windows_amd64",
+		},
+		{
+			name: "falls back to the emulated build",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{"aer_darwin_amd64_v1.0.0.zip": fakegithub.Zip(t, fakeBinary)})
+			},
+			config: func(cfg *config) {
+				cfg.RunnerOS, cfg.RunnerArch, cfg.SkipVerify = "macOS", "ARM64", true
+			},
+		},
+		{
+			name: "rejects a checksum mismatch",
+			assets: func(t *testing.T) map[string][]byte {
+				assets := withChecksums(map[string][]byte{archive: []byte("published")})
+				assets[archive] = fakegithub.Zip(t, fakeBinary)
+				return assets
+			},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name: "reports a missing archive",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{"aer_plan9_amd64_v1.0.0.zip": []byte("other")})
+			},
+			wantErr: "not found",
+		},
+		{
+			name: "rejects a corrupt zip",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{archive: []byte("not a zip")})
+			},
+			wantErr: "extract binary: zip: not a valid zip file",
+		},
+		{
+			name: "rejects an archive without the binary",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakegithub.ZipFile{Name: "README.md", Body: "hi"})})
+			},
+			wantErr: "aer not found in archive",
+		},
+		{
+			name: "rejects a binary reporting another version",
+			assets: func(t *testing.T) map[string][]byte {
+				old := fakeBinary
+				old.Body = "#!/bin/sh\necho \"aer version v0.9.0\"\n"
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, old)})
+			},
+			wantErr: "reports version v0.9.0",
+		},
+		{
+			name: "explains rate limiting",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+			},
+			setup:   func(s *fakegithub.Server) { s.FailPath(".zip", http.StatusForbidden) },
+			wantErr: "API rate limit exceeded",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakegithub.New(t)
+			server.AddRelease(testRepo, fakegithub.Release{Tag: "v1.0.0", Assets: tc.assets(t)})
+			if tc.setup != nil {
+				tc.setup(server)
+			}
+
+			cfg := config{Repo: testRepo, Version: "v1.0.0", Dest: t.TempDir(), GitHub: server.Client()}
+			if tc.config != nil {
+				tc.config(&cfg)
+			}
+			env := ghenv.NewMemory()
+
+			err := run(cfg, env)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				if len(env.Path) != 0 || len(env.Outputs) != 0 {
+					t.Fatalf("failed install must not update PATH or outputs: %+v", env)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if len(env.Path) != 1 || env.Path[0] != cfg.Dest {
+				t.Fatalf("expected %s on PATH, got %v", cfg.Dest, env.Path)
+			}
+			if env.Outputs["version"] != "v1.0.0" || env.Outputs["binary"] != filepath.Join(cfg.Dest, "aer") {
+				t.Fatalf("unexpected outputs %v", env.Outputs)
+			}
+			if tc.check != nil {
+				tc.check(t, cfg, env)
+			}
+		})
+	}
+}
+
+func TestRunRequiresRepoAndVersion(t *testing.T) {
+	err := run(config{Dest: t.TempDir()}, ghenv.NewMemory())
+	if err == nil || !strings.Contains(err.Error(), "--repo and --version") {
+		t.Fatalf("expected missing input error, got %v", err)
+	}
+}
+
+// testReleaseKey swaps the pinned release key for a fresh minisign key for
+// the rest of the test and returns a function that signs a release's
+// SHA256SUMS with it.
+func testReleaseKey(t *testing.T) (sign func(assets map[string][]byte) map[string][]byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	id := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	key, err := release.ParsePublicKey([]byte("untrusted comment: minisign public key 0807060504030201\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), id...), pub...)) + "\n"))
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+	saved := releaseKey
+	releaseKey = func() release.PublicKey { return key }
+	t.Cleanup(func() { releaseKey = saved })
+
+	return func(assets map[string][]byte) map[string][]byte {
+		digest := blake2b.Sum512(assets["SHA256SUMS-v1.0.0"])
+		sig := append(append([]byte("ED"), id...), ed25519.Sign(priv, digest[:])...)
+		comment := "timestamp:1700000000\tfile:SHA256SUMS-v1.0.0"
+		global := ed25519.Sign(priv, append(append([]byte{}, sig[10:]...), comment...))
+		assets["SHA256SUMS-v1.0.0.minisig"] = []byte("untrusted comment: signature from minisign secret key\n" +
+			base64.StdEncoding.EncodeToString(sig) + "\n" +
+			"trusted comment: " + comment + "\n" +
+			base64.StdEncoding.EncodeToString(global) + "\n")
+		return assets
+	}
+}
+
+func TestRunRequiresReleaseKeySignature(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aer uses a shell script")
+	}
+	archive := hostArchive(t)
+	sign := testReleaseKey(t)
+
+	cases := []struct {
+		name    string
+		assets  func(t *testing.T) map[string][]byte
+		wantErr string
+	}{
+		{
+			name: "installs checksums signed with the release key",
+			assets: func(t *testing.T) map[string][]byte {
+				return sign(withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)}))
+			},
+		},
+		{
+			name: "checks a signed manifest against the checksums",
+			assets: func(t *testing.T) map[string][]byte {
+				archives := map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)}
+				assets := sign(withChecksums(archives))
+				assets["manifest-v1.0.0.json"] = fakegithub.Manifest(t, "v1.0.0", archives)
+				return assets
+			},
+		},
+		{
+			name: "rejects tampered checksums",
+			assets: func(t *testing.T) map[string][]byte {
+				assets := sign(withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)}))
+				assets["SHA256SUMS-v1.0.0"] = append(assets["SHA256SUMS-v1.0.0"], "\n"...)
+				return assets
+			},
+			wantErr: "verify checksums signature",
+		},
+		{
+			name: "rejects unsigned checksums",
+			assets: func(t *testing.T) map[string][]byte {
+				return withChecksums(map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+			},
+			wantErr: "SHA256SUMS-v1.0.0.minisig",
+		},
+		{
+			name: "rejects a signature without checksums",
+			assets: func(t *testing.T) map[string][]byte {
+				assets := withManifest(t, map[string][]byte{archive: fakegithub.Zip(t, fakeBinary)})
+				assets["SHA256SUMS-v1.0.0.minisig"] = sign(withChecksums(nil))["SHA256SUMS-v1.0.0.minisig"]
+				return assets
+			},
+			wantErr: "read release checksums",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakegithub.New(t)
+			server.AddRelease(testRepo, fakegithub.Release{Tag: "v1.0.0", Assets: tc.assets(t)})
+			cfg := config{Repo: testRepo, Version: "v1.0.0", Dest: t.TempDir(), GitHub: server.Client(), RequireSignature: true}
+
+			err := run(cfg, ghenv.NewMemory())
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+		})
+	}
+}
@@ -1,121 +1,222 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
-	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+
+	"aer/internal/actionlog"
+	"aer/internal/ghenv"
+	"aer/internal/github"
+	"aer/internal/release"
 )
 
-func main() {
-	var requested string
-	var repo string
-	var fallback string
+// config holds the resolve command's inputs.
+type config struct {
+	Requested  string
+	Repo       string
+	Fallback   string
+	FromFile   string
+	FromDir    string
+	ProjectDir string
+	Lockfile   string
+	UpdateLock bool
+	GitHub     *github.Client
+}
 
-	flag.StringVar(&requested, "requested", "", "requested release tag (use 'latest' to resolve dynamically)")
-	flag.StringVar(&repo, "repo", "", "value of github.action_repository")
-	flag.StringVar(&fallback, "fallback", "", "value of github.repository (fallback)")
+func main() {
+	var cfg config
+	var debug bool
+
+	flag.StringVar(&cfg.Requested, "requested", "", "requested release tag (use 'latest' to resolve dynamically; empty reads the project's pin file)")
+	flag.StringVar(&cfg.Repo, "repo", "", "value of github.action_repository")
+	flag.StringVar(&cfg.Fallback, "fallback", "", "value of github.repository (fallback)")
+	flag.StringVar(&cfg.FromFile, "from-file", "", "local release archive; with 'local' the version is read from its name")
+	flag.StringVar(&cfg.FromDir, "from-dir", "", "directory of release assets; with 'local' the version is read from its contents")
+	flag.StringVar(&cfg.ProjectDir, "project-dir", ".", "project directory searched for .aer-version or sfdx-project.json when --requested is empty")
+	flag.StringVar(&cfg.Lockfile, "lockfile", "", "aer.lock to read the version from, or to write after resolving when it does not exist")
+	flag.BoolVar(&cfg.UpdateLock, "update-lock", false, "re-resolve the version and rewrite --lockfile even if it exists")
+	flag.BoolVar(&debug, "debug", actionlog.RunnerDebug(), "log debug output (also enabled by RUNNER_DEBUG=1)")
 	flag.Parse()
+	actionlog.SetDebug(debug)
 
+	cfg.GitHub = github.NewClient()
+	if err := run(cfg, ghenv.FromEnvironment()); err != nil {
+		actionlog.Fatal(err)
+	}
+}
+
+// run resolves the release to install and writes the version and repo step
+// outputs.
+func run(cfg config, env ghenv.Env) error {
+	repo := cfg.Repo
 	if repo == "" {
-		repo = fallback
+		repo = cfg.Fallback
 	}
 	if repo == "" {
-		log.Fatal("unable to determine repository that hosts the action")
+		return errors.New("unable to determine repository that hosts the action")
 	}
 
-	version := strings.TrimSpace(requested)
-	if version == "" || version == "latest" {
-		resolved, err := resolveLatestTag(repo)
+	version := strings.TrimSpace(cfg.Requested)
+	origin := "the version input"
+	if version == "" {
+		pinned, pinSource, err := readPinnedVersion(cfg.ProjectDir)
 		if err != nil {
-			log.Fatalf("resolve latest release: %v", err)
+			return fmt.Errorf("read pinned version: %w", err)
+		}
+		if pinned != "" {
+			version, origin = pinned, pinSource
+		} else {
+			origin = "the default (no version input or pin file)"
 		}
-		version = resolved
 	}
 
-	output := os.Getenv("GITHUB_OUTPUT")
-	if output == "" {
-		log.Fatal("GITHUB_OUTPUT is not set")
+	locked := false
+	if cfg.Lockfile != "" && !cfg.UpdateLock {
+		lock, err := release.ReadLock(cfg.Lockfile)
+		switch {
+		case err == nil:
+			if isExactVersion(version) && version != lock.Version {
+				return fmt.Errorf("%s pins %s but %s requests %s; re-run with --update-lock to change it", cfg.Lockfile, lock.Version, origin, version)
+			}
+			version, origin, locked = lock.Version, cfg.Lockfile, true
+		case !errors.Is(err, os.ErrNotExist):
+			return fmt.Errorf("read lockfile: %w", err)
+		}
 	}
+	actionlog.Infof("Using version %q from %s", orDefault(version, "latest"), origin)
 
-	file, err := os.OpenFile(output, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
-	if err != nil {
-		log.Fatalf("open GITHUB_OUTPUT: %v", err)
+	if !locked {
+		offline := cfg.FromFile != "" || cfg.FromDir != ""
+		if offline && (version == "" || version == "latest") {
+			// Offline installs cannot ask GitHub for the latest release.
+			version = "local"
+		}
+		if version == "local" {
+			resolved, err := resolveLocalVersion(cfg.FromFile, cfg.FromDir)
+			if err != nil {
+				return fmt.Errorf("resolve local release: %w", err)
+			}
+			version = resolved
+		} else if version == "" || version == "latest" {
+			resolved, err := cfg.GitHub.LatestTag(repo)
+			if err != nil {
+				return fmt.Errorf("resolve latest release: %w", err)
+			}
+			version = resolved
+		}
+
+		if cfg.Lockfile != "" {
+			if err := writeLock(cfg.GitHub, cfg.Lockfile, repo, version, localAssetDir(cfg.FromFile, cfg.FromDir)); err != nil {
+				return fmt.Errorf("write lockfile: %w", err)
+			}
+			actionlog.Infof("Recorded %s and its asset digests in %s", version, cfg.Lockfile)
+		}
 	}
-	defer file.Close()
 
-	if _, err := fmt.Fprintf(file, "version=%s\nrepo=%s\n", version, repo); err != nil {
-		log.Fatalf("write outputs: %v", err)
+	if err := env.SetOutput("version", version); err != nil {
+		return fmt.Errorf("write outputs: %w", err)
 	}
+	if err := env.SetOutput("repo", repo); err != nil {
+		return fmt.Errorf("write outputs: %w", err)
+	}
+
+	actionlog.Infof("Resolved release %q in repository %q", version, repo)
+	return nil
+}
+
+// isExactVersion reports whether version names a specific release rather
+// than one resolved at run time.
+func isExactVersion(version string) bool {
+	return version != "" && version != "latest" && version != "local"
+}
 
-	fmt.Printf("Resolved release %q in repository %q\n", version, repo)
+// localAssetDir returns the directory holding pre-staged assets, if any.
+func localAssetDir(fromFile, fromDir string) string {
+	if fromFile != "" {
+		return filepath.Dir(fromFile)
+	}
+	return fromDir
 }
 
-func resolveLatestTag(repo string) (string, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo), nil)
+// writeLock records version and the digests of its archives, read from
+// localDir when set and otherwise from the GitHub release.
+func writeLock(client *github.Client, path, repo, version, localDir string) error {
+	sums, err := releaseDigests(client, repo, version, localDir)
 	if err != nil {
-		return "", err
+		return err
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	resp, err := http.DefaultClient.Do(req)
+	lock, err := release.NewLock(repo, version, sums)
 	if err != nil {
-		return "", err
+		return err
 	}
-	defer resp.Body.Close()
+	return lock.Write(path)
+}
 
-	if resp.StatusCode == http.StatusNotFound {
-		return latestFromList(repo)
-	}
-	if resp.StatusCode >= 400 {
-		return "", fmt.Errorf("unexpected HTTP status %s", resp.Status)
+// releaseDigests reads the archive digests from the release manifest, or from
+// SHA256SUMS-<version> for releases published without one.
+func releaseDigests(client *github.Client, repo, version, localDir string) (release.Checksums, error) {
+	name := release.ManifestName(version)
+	data, err := readAsset(client, repo, version, localDir, name)
+	switch {
+	case err == nil:
+		manifest, err := release.ParseManifest(data)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.Version != version {
+			return nil, fmt.Errorf("%s describes version %s", name, manifest.Version)
+		}
+		return manifest.Checksums(), nil
+	case !errors.Is(err, github.ErrNotFound) && !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("read %s: %w", name, err)
 	}
 
-	var payload struct {
-		Tag string `json:"tag_name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return "", err
-	}
-	if payload.Tag == "" {
-		return "", fmt.Errorf("latest release response missing tag_name")
+	name = release.ChecksumsName(version)
+	data, err = readAsset(client, repo, version, localDir, name)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
 	}
-
-	return payload.Tag, nil
+	return release.ParseChecksums(data)
 }
 
-func latestFromList(repo string) (string, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=1", repo), nil)
-	if err != nil {
-		return "", err
+// readAsset reads a release asset from localDir when set and otherwise from
+// the GitHub release.
+func readAsset(client *github.Client, repo, version, localDir, name string) ([]byte, error) {
+	if localDir != "" {
+		return os.ReadFile(filepath.Join(localDir, name))
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
+	var buf bytes.Buffer
+	if err := client.DownloadAsset(repo, version, name, &buf); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return buf.Bytes(), nil
+}
 
-	if resp.StatusCode >= 400 {
-		return "", fmt.Errorf("unexpected HTTP status %s", resp.Status)
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
 	}
+	return value
+}
 
-	var releases []struct {
-		Tag   string `json:"tag_name"`
-		Draft bool   `json:"draft"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
-		return "", err
-	}
-	for _, rel := range releases {
-		if rel.Draft {
-			continue
-		}
-		if rel.Tag != "" {
-			return rel.Tag, nil
+// resolveLocalVersion reads the release version from a pre-staged archive's
+// name or from the assets in a directory.
+func resolveLocalVersion(fromFile, fromDir string) (string, error) {
+	switch {
+	case fromFile != "":
+		_, version, ok := release.ParseArchiveName(filepath.Base(fromFile))
+		if !ok {
+			return "", fmt.Errorf("%s does not follow the aer_<os>_<arch>_<version>.zip naming scheme", fromFile)
 		}
+		return version, nil
+	case fromDir != "":
+		return release.VersionFromDir(fromDir)
+	default:
+		return "", fmt.Errorf("version 'local' requires --from-file or --from-dir")
 	}
-	return "", fmt.Errorf("no published releases found")
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aer/internal/fakegithub"
+	"aer/internal/ghenv"
+	"aer/internal/release"
+)
+
+const testRepo = "octoberswimmer/aer-dist"
+
+func TestRunAgainstFakeGitHub(t *testing.T) {
+	archives := map[string][]byte{"aer_linux_amd64_v1.1.0.zip": []byte("zip")}
+	sums := fakegithub.Checksums(archives)
+
+	cases := []struct {
+		name     string
+		releases []fakegithub.Release
+		fail     map[string]int
+		project  map[string]string // files in the project directory
+		config   func(cfg *config, dir string)
+		want     string
+		wantErr  string
+		requests int
+	}{
+		{
+			name:   "explicit version skips the API",
+			config: func(cfg *config, dir string) { cfg.Requested = "v0.0.9" },
+			want:   "v0.0.9",
+		},
+		{
+			name:     "latest release",
+			releases: []fakegithub.Release{{Tag: "v1.0.0"}, {Tag: "v1.1.0"}},
+			config:   func(cfg *config, dir string) { cfg.Requested = "latest" },
+			want:     "v1.1.0",
+			requests: 1,
+		},
+		{
+			name:     "empty input falls back to latest",
+			releases: []fakegithub.Release{{Tag: "v1.1.0"}},
+			want:     "v1.1.0",
+			requests: 1,
+		},
+		{
+			name:     "pin file wins over latest",
+			releases: []fakegithub.Release{{Tag: "v1.1.0"}},
+			project:  map[string]string{".aer-version": "v1.0.0\n"},
+			want:     "v1.0.0",
+		},
+		{
+			name:     "latest falls back to the release list, skipping drafts",
+			releases: []fakegithub.Release{{Tag: "v2.0.0-rc.1", Prerelease: true}, {Tag: "v2.0.0", Draft: true}},
+			want:     "v2.0.0-rc.1",
+			requests: 2,
+		},
+		{
+			name:    "no published releases",
+			wantErr: "resolve latest release: no published releases found",
+		},
+		{
+			name:     "rate limited",
+			releases: []fakegithub.Release{{Tag: "v1.1.0"}},
+			fail:     map[string]int{"/releases/latest": http.StatusForbidden},
+			wantErr:  "API rate limit exceeded",
+		},
+		{
+			name:     "writes a lock file from the release checksums",
+			releases: []fakegithub.Release{{Tag: "v1.1.0", Assets: map[string][]byte{"SHA256SUMS-v1.1.0": sums}}},
+			config:   func(cfg *config, dir string) { cfg.Lockfile = filepath.Join(dir, "aer.lock") },
+			want:     "v1.1.0",
+			requests: 3,
+		},
+		{
+			name:     "writes a lock file from the release manifest",
+			releases: []fakegithub.Release{{Tag: "v1.1.0", Assets: map[string][]byte{"manifest-v1.1.0.json": fakegithub.Manifest(t, "v1.1.0", archives)}}},
+			config:   func(cfg *config, dir string) { cfg.Lockfile = filepath.Join(dir, "aer.lock") },
+			want:     "v1.1.0",
+			requests: 2,
+		},
+		{
+			name:     "lock file needs the release checksums",
+			releases: []fakegithub.Release{{Tag: "v1.1.0"}},
+			config:   func(cfg *config, dir string) { cfg.Lockfile = filepath.Join(dir, "aer.lock") },
+			wantErr:  "write lockfile: read SHA256SUMS-v1.1.0",
+		},
+		{
+			name:     "existing lock file pins the version",
+			releases: []fakegithub.Release{{Tag: "v1.1.0"}},
+			project:  map[string]string{"aer.lock": `{"version": "v1.0.0", "repository": "octoberswimmer/aer-dist", "assets": []}`},
+			config:   func(cfg *config, dir string) { cfg.Lockfile = filepath.Join(dir, "aer.lock") },
+			want:     "v1.0.0",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakegithub.New(t)
+			for _, rel := range tc.releases {
+				server.AddRelease(testRepo, rel)
+			}
+			for suffix, status := range tc.fail {
+				server.FailPath(suffix, status)
+			}
+			dir := t.TempDir()
+			for name, content := range tc.project {
+				writeFile(t, dir, name, content)
+			}
+
+			cfg := config{Repo: testRepo, ProjectDir: dir, GitHub: server.Client()}
+			if tc.config != nil {
+				tc.config(&cfg, dir)
+			}
+			env := ghenv.NewMemory()
+
+			err := run(cfg, env)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if env.Outputs["version"] != tc.want || env.Outputs["repo"] != testRepo {
+				t.Fatalf("unexpected outputs %v", env.Outputs)
+			}
+			if got := len(server.Requests()); got != tc.requests {
+				t.Fatalf("expected %d requests, got %v", tc.requests, server.Requests())
+			}
+			if cfg.Lockfile != "" {
+				lock, err := release.ReadLock(cfg.Lockfile)
+				if err != nil || lock.Version != tc.want {
+					t.Fatalf("unexpected lock %+v (%v)", lock, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRunUsesFallbackRepository(t *testing.T) {
+	env := ghenv.NewMemory()
+	if err := run(config{Requested: "v1", Fallback: "someone/fork"}, env); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if env.Outputs["repo"] != "someone/fork" {
+		t.Fatalf("unexpected outputs %v", env.Outputs)
+	}
+
+	if err := run(config{Requested: "v1"}, env); err == nil {
+		t.Fatal("expected an error without a repository")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"aer/internal/actionlog"
+)
+
+const versionFileName = ".aer-version"
+
+// readPinnedVersion looks for a version pinned in the project, first in
+// .aer-version and then under the "aer" key of sfdx-project.json. It returns
+// the version and the file it came from, or empty strings when nothing is
+// pinned. Only an explicit .aer-version is an error when unusable: most
+// projects have an sfdx-project.json that never mentions aer, so one that
+// cannot be read or parsed is logged and the default version used.
+func readPinnedVersion(projectDir string) (string, string, error) {
+	path := filepath.Join(projectDir, versionFileName)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		version := parseVersionFile(data)
+		if version == "" {
+			return "", "", fmt.Errorf("%s does not contain a version", path)
+		}
+		return version, path, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", "", err
+	}
+
+	path = filepath.Join(projectDir, "sfdx-project.json")
+	data, err = os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		actionlog.Warningf("Ignoring %s: %v", path, err)
+		return "", "", nil
+	}
+	version, err := parseProjectVersion(data)
+	if err != nil {
+		actionlog.Warningf("Ignoring the aer version in %s: %v", path, err)
+		return "", "", nil
+	}
+	if version == "" {
+		return "", "", nil
+	}
+	return version, path + ` ("aer" key)`, nil
+}
+
+// parseVersionFile returns the first line that is neither blank nor a
+// comment.
+func parseVersionFile(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+// parseProjectVersion reads the "aer" key of sfdx-project.json, which may be
+// a version string or an object with a "version" field.
+func parseProjectVersion(data []byte) (string, error) {
+	var project struct {
+		Aer json.RawMessage `json:"aer"`
+	}
+	// Editors on Windows often save the file with a byte order mark.
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if err := json.Unmarshal(data, &project); err != nil {
+		return "", err
+	}
+	if len(project.Aer) == 0 || string(project.Aer) == "null" {
+		return "", nil
+	}
+
+	var version string
+	if err := json.Unmarshal(project.Aer, &version); err == nil {
+		return strings.TrimSpace(version), nil
+	}
+	var settings struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(project.Aer, &settings); err != nil {
+		return "", fmt.Errorf(`"aer" must be a version string or an object with a "version" field`)
+	}
+	return strings.TrimSpace(settings.Version), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestReadPinnedVersionPrefersVersionFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".aer-version", "# pinned by renovate\n\n  v0.0.101  \n")
+	writeFile(t, dir, "sfdx-project.json", `{"aer": "v0.0.99"}`)
+
+	version, source, err := readPinnedVersion(dir)
+	if err != nil {
+		t.Fatalf("readPinnedVersion: %v", err)
+	}
+	if version != "v0.0.101" || !strings.HasSuffix(source, ".aer-version") {
+		t.Fatalf("unexpected pin %q from %q", version, source)
+	}
+}
+
+func TestReadPinnedVersionFromProjectFile(t *testing.T) {
+	cases := map[string]string{
+		`{"packageDirectories": [], "aer": "latest"}`:              "latest",
+		`{"packageDirectories": [], "aer": {"version": "v1.2.3"}}`: "v1.2.3",
+		`{"packageDirectories": []}`:                               "",
+		"\ufeff{\"aer\": \"v1.2.4\"}":                              "v1.2.4",
+	}
+	for content, want := range cases {
+		dir := t.TempDir()
+		writeFile(t, dir, "sfdx-project.json", content)
+		version, source, err := readPinnedVersion(dir)
+		if err != nil {
+			t.Fatalf("%s: %v", content, err)
+		}
+		if version != want {
+			t.Fatalf("%s: expected %q, got %q", content, want, version)
+		}
+		if want != "" && !strings.Contains(source, "sfdx-project.json") {
+			t.Fatalf("%s: unexpected source %q", content, source)
+		}
+	}
+}
+
+func TestReadPinnedVersionErrors(t *testing.T) {
+	empty := t.TempDir()
+	writeFile(t, empty, ".aer-version", "# nothing here\n")
+	if _, _, err := readPinnedVersion(empty); err == nil {
+		t.Fatal("expected error for an empty .aer-version")
+	}
+
+	version, source, err := readPinnedVersion(t.TempDir())
+	if err != nil || version != "" || source != "" {
+		t.Fatalf("expected no pin, got %q from %q (%v)", version, source, err)
+	}
+}
+
+func TestReadPinnedVersionIgnoresMalformedProjectFile(t *testing.T) {
+	for _, content := range []string{
+		`{"aer": 42}`,
+		`{"packageDirectories": [{"path": "force-app"},], "aer": "v1.2.3"}`,
+		`not json`,
+	} {
+		dir := t.TempDir()
+		writeFile(t, dir, "sfdx-project.json", content)
+		version, source, err := readPinnedVersion(dir)
+		if err != nil || version != "" || source != "" {
+			t.Fatalf("%s: expected the default version, got %q from %q (%v)", content, version, source, err)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"aer/internal/actionlog"
+	"aer/internal/apexsource"
+)
+
+// selection is the outcome of choosing the tests affected by a change set.
+type selection struct {
+	// Classes are the affected test classes, sorted.
+	Classes []string
+	// FullRun explains why every test must run; empty when Classes applies.
+	FullRun string
+}
+
+// selectAffectedTests picks the test classes exercising the Apex changed
+// since the merge base of cfg.DiffBase and HEAD. Classes, triggers and
+// object metadata under the source paths are mapped to tests through a
+// dependency index: cfg.DependencyIndex when given, and otherwise class
+// references found by scanning the source. Any other metadata change, a
+// change outside the source paths other than documentation (such as
+// sfdx-project.json, .forceignore or the dependency index), or a diff git
+// cannot produce, such as with a shallow checkout, runs everything.
+func selectAffectedTests(cfg runConfig) (selection, error) {
+	files, err := changedFiles(cfg.WorkDir, cfg.DiffBase)
+	if err != nil {
+		actionlog.Warningf("Cannot diff against %s: %v", cfg.DiffBase, err)
+		return selection{FullRun: "the diff against " + cfg.DiffBase + " is unavailable"}, nil
+	}
+
+	var changed []change
+	for _, file := range files {
+		if !underSources(cfg.WorkDir, file, cfg.Sources) {
+			if !isDoc(file) {
+				return selection{FullRun: file + " changed outside the source paths"}, nil
+			}
+			actionlog.Debugf("Ignoring %s: documentation outside the source paths", file)
+			continue
+		}
+		c := classifyChange(file)
+		switch c.Kind {
+		case changeIgnored:
+			actionlog.Debugf("Ignoring %s: does not affect Apex tests", file)
+		case changeOther:
+			return selection{FullRun: file + " changed"}, nil
+		default:
+			changed = append(changed, c)
+		}
+	}
+	if len(changed) == 0 {
+		return selection{}, nil
+	}
+
+	index, err := scanApex(cfg.WorkDir, cfg.Sources)
+	if err != nil {
+		return selection{}, err
+	}
+	var covered map[string][]string
+	if cfg.DependencyIndex != "" {
+		if covered, err = readDependencyIndex(cfg.DependencyIndex); err != nil {
+			return selection{}, err
+		}
+	}
+	return selection{Classes: index.affectedTests(changed, covered)}, nil
+}
+
+// narrowClasses limits the affected tests to this shard's classes when
+// sharding.
+func narrowClasses(cfg runConfig, affected []string) []string {
+	if !cfg.Sharded {
+		return affected
+	}
+	shard := make(map[string]bool, len(cfg.Classes))
+	for _, class := range cfg.Classes {
+		shard[strings.ToLower(class)] = true
+	}
+	var classes []string
+	for _, class := range affected {
+		if shard[strings.ToLower(class)] {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+func pluralClasses(n int) string {
+	if n == 1 {
+		return "class"
+	}
+	return "classes"
+}
+
+// docExtensions are the documentation files that never affect tests.
+var docExtensions = map[string]bool{".md": true, ".txt": true, ".rst": true, ".adoc": true}
+
+// isDoc reports whether file, relative to the work directory, is
+// documentation: a Markdown or text file, or anything under a docs
+// directory.
+func isDoc(file string) bool {
+	if docExtensions[strings.ToLower(path.Ext(file))] {
+		return true
+	}
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		if dir == "docs" || dir == "doc" {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFiles lists the files changed between the merge base of base and
+// HEAD, relative to workDir.
+func changedFiles(workDir, base string) ([]string, error) {
+	cmd := exec.Command("git", "diff", "-z", "--name-only", "--no-renames", "--relative", base+"...HEAD", "--")
+	cmd.Dir = workDir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git diff: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+	var files []string
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// underSources reports whether file, relative to workDir, is inside one of
+// the source paths.
+func underSources(workDir, file string, sources []string) bool {
+	for _, source := range sources {
+		if filepath.IsAbs(source) && workDir != "" {
+			rel, err := filepath.Rel(workDir, source)
+			if err != nil {
+				continue
+			}
+			source = rel
+		}
+		source = filepath.ToSlash(filepath.Clean(source))
+		if source == "." || file == source || strings.HasPrefix(file, source+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+type changeKind int
+
+const (
+	changeIgnored changeKind = iota
+	changeClass
+	changeTrigger
+	changeObject
+	changeOther
+)
+
+// change is a changed file reduced to the Apex component it belongs to.
+type change struct {
+	Kind changeKind
+	Name string
+}
+
+// ignoredDirs hold source that Apex tests cannot exercise.
+var ignoredDirs = map[string]bool{"lwc": true, "aura": true, "__tests__": true}
+
+// classifyChange maps a changed file to the class, trigger or object it
+// defines, in either source or metadata API format.
+func classifyChange(file string) change {
+	base := path.Base(file)
+	switch {
+	case strings.HasSuffix(base, ".cls"), strings.HasSuffix(base, ".cls-meta.xml"):
+		return change{Kind: changeClass, Name: strings.SplitN(base, ".", 2)[0]}
+	case strings.HasSuffix(base, ".trigger"), strings.HasSuffix(base, ".trigger-meta.xml"):
+		return change{Kind: changeTrigger, Name: strings.SplitN(base, ".", 2)[0]}
+	case strings.HasSuffix(base, ".md"):
+		return change{Kind: changeIgnored}
+	}
+	parts := strings.Split(file, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ".") || ignoredDirs[part] {
+			return change{Kind: changeIgnored}
+		}
+		// objects/Invoice__c/fields/Total__c.field-meta.xml in source format,
+		// objects/Invoice__c.object in the metadata API format.
+		if part == "objects" && i+1 < len(parts) {
+			return change{Kind: changeObject, Name: strings.TrimSuffix(parts[i+1], ".object")}
+		}
+	}
+	return change{Kind: changeOther}
+}
+
+// apexUnit is a class or trigger found in the source.
+type apexUnit struct {
+	Name string
+	Test bool
+	// Object is the sObject a trigger fires on.
+	Object string
+	// Refs are the lowercased identifiers the unit's code uses.
+	Refs map[string]bool
+}
+
+// apexIndex is the lightweight dependency index built by scanning the
+// source: each unit depends on every class, trigger or object whose name
+// appears as an identifier in its code.
+type apexIndex struct {
+	units []apexUnit
+}
+
+var (
+	triggerDeclPattern = regexp.MustCompile(`(?i)\btrigger\s+\w+\s+on\s+(\w+)`)
+	identifierPattern  = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+func scanApex(workDir string, sources []string) (*apexIndex, error) {
+	index := &apexIndex{}
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		root := source
+		if !filepath.IsAbs(root) && workDir != "" {
+			root = filepath.Join(workDir, root)
+		}
+		err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" || d.Name() == "node_modules" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			ext := filepath.Ext(file)
+			if ext != ".cls" && ext != ".trigger" {
+				return nil
+			}
+			if seen[file] {
+				return nil
+			}
+			seen[file] = true
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			code := apexsource.Strip(string(data))
+			unit := apexUnit{Name: strings.TrimSuffix(d.Name(), ext), Refs: make(map[string]bool)}
+			if ext == ".cls" {
+				unit.Test = apexsource.IsTestClass(code)
+			} else if m := triggerDeclPattern.FindStringSubmatch(code); m != nil {
+				unit.Object = m[1]
+			}
+			for _, ident := range identifierPattern.FindAllString(code, -1) {
+				unit.Refs[strings.ToLower(ident)] = true
+			}
+			index.units = append(index.units, unit)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %w", source, err)
+		}
+	}
+	return index, nil
+}
+
+// affectedTests returns the test classes affected by the changes. Without a
+// coverage index, a test is affected when it depends, directly or through
+// other classes and triggers, on a changed component; a trigger depending on
+// one makes its object count as changed, since DML on the object runs it.
+// With one, a test is affected when it covers a changed class or trigger, or
+// uses a changed component directly; tests missing from the index fall back
+// to the scan.
+func (x *apexIndex) affectedTests(changes []change, covered map[string][]string) []string {
+	seeds := make(map[string]bool)
+	for _, c := range changes {
+		seeds[strings.ToLower(c.Name)] = true
+	}
+	for _, unit := range x.units {
+		if unit.Object != "" && seeds[strings.ToLower(unit.Object)] {
+			seeds[strings.ToLower(unit.Name)] = true
+		}
+		if unit.Object != "" && seeds[strings.ToLower(unit.Name)] {
+			seeds[strings.ToLower(unit.Object)] = true
+		}
+	}
+	closure := x.dependents(seeds)
+
+	var tests []string
+	for _, unit := range x.units {
+		if !unit.Test {
+			continue
+		}
+		name := strings.ToLower(unit.Name)
+		classes, indexed := covered[name]
+		affected := closure[name]
+		if indexed {
+			affected = seeds[name] || intersects(unit.Refs, seeds)
+			for _, class := range classes {
+				affected = affected || seeds[strings.ToLower(class)]
+			}
+		}
+		if affected {
+			tests = append(tests, unit.Name)
+		}
+	}
+	sort.Strings(tests)
+	return tests
+}
+
+// dependents returns the seeds and every unit depending on them, adding a
+// trigger's object along with the trigger.
+func (x *apexIndex) dependents(seeds map[string]bool) map[string]bool {
+	affected := make(map[string]bool, len(seeds))
+	for name := range seeds {
+		affected[name] = true
+	}
+	for grew := true; grew; {
+		grew = false
+		for _, unit := range x.units {
+			name := strings.ToLower(unit.Name)
+			if affected[name] || !intersects(unit.Refs, affected) {
+				continue
+			}
+			affected[name] = true
+			if unit.Object != "" {
+				affected[strings.ToLower(unit.Object)] = true
+			}
+			grew = true
+		}
+	}
+	return affected
+}
+
+func intersects(refs, names map[string]bool) bool {
+	for name := range names {
+		if refs[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// readDependencyIndex reads a JSON object mapping each test class to the
+// classes and triggers it covers, such as one derived from per-test coverage
+// of an earlier full run. Names are matched case-insensitively.
+func readDependencyIndex(filename string) (map[string][]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string][]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse dependency index %s: %w", filename, err)
+	}
+	index := make(map[string][]string, len(raw))
+	for test, classes := range raw {
+		index[strings.ToLower(test)] = classes
+	}
+	return index, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apexProject is a small org: a controller and its service, an Invoice__c
+// trigger delegating to a handler, and a test for each.
+var apexProject = map[string]string{
+	"force-app/main/default/classes/AccountService.cls":        "public class AccountService { public static void rename(Account a) {} }",
+	"force-app/main/default/classes/AccountController.cls":     "public with sharing class AccountController { public void save() { AccountService.rename(null); } }",
+	"force-app/main/default/classes/AccountControllerTest.cls": "@IsTest\nprivate class AccountControllerTest { @IsTest static void saves() { new AccountController().save(); } }",
+	"force-app/main/default/classes/InvoiceHandler.cls":        "public class InvoiceHandler { public static void total(List<Invoice__c> rows) {} }",
+	"force-app/main/default/classes/InvoiceTest.cls":           "@IsTest\nprivate class InvoiceTest { @IsTest static void totals() { insert new Invoice__c(); } }",
+	"force-app/main/default/classes/ReportTest.cls":            "@IsTest\nprivate class ReportTest { // uses AccountService in a comment only\n @IsTest static void runs() { System.assert(true, 'InvoiceHandler'); } }",
+	"force-app/main/default/triggers/InvoiceTrigger.trigger":   "trigger InvoiceTrigger on Invoice__c (before insert) { InvoiceHandler.total(Trigger.new); }",
+}
+
+func writeProject(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClassifyChange(t *testing.T) {
+	cases := map[string]change{
+		"force-app/main/default/classes/AccountService.cls":                        {Kind: changeClass, Name: "AccountService"},
+		"force-app/main/default/classes/AccountService.cls-meta.xml":               {Kind: changeClass, Name: "AccountService"},
+		"src/triggers/InvoiceTrigger.trigger":                                      {Kind: changeTrigger, Name: "InvoiceTrigger"},
+		"force-app/main/default/objects/Invoice__c/fields/Total__c.field-meta.xml": {Kind: changeObject, Name: "Invoice__c"},
+		"src/objects/Invoice__c.object":                                            {Kind: changeObject, Name: "Invoice__c"},
+		"force-app/main/default/lwc/invoiceList/invoiceList.js":                    {Kind: changeIgnored},
+		"force-app/README.md":                                                      {Kind: changeIgnored},
+		"force-app/main/default/flows/Invoice_Approval.flow-meta.xml":              {Kind: changeOther},
+	}
+	for file, want := range cases {
+		if got := classifyChange(file); got != want {
+			t.Errorf("classifyChange(%s) = %+v, want %+v", file, got, want)
+		}
+	}
+}
+
+func TestAffectedTests(t *testing.T) {
+	dir := t.TempDir()
+	writeProject(t, dir, apexProject)
+	index, err := scanApex(dir, []string{"force-app"})
+	if err != nil {
+		t.Fatalf("scanApex: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		changes []change
+		covered map[string][]string
+		want    []string
+	}{
+		{
+			name:    "follows class references",
+			changes: []change{{Kind: changeClass, Name: "AccountService"}},
+			want:    []string{"AccountControllerTest"},
+		},
+		{
+			name:    "follows a handler through its trigger to the object",
+			changes: []change{{Kind: changeClass, Name: "InvoiceHandler"}},
+			want:    []string{"InvoiceTest"},
+		},
+		{
+			name:    "runs tests using a changed object",
+			changes: []change{{Kind: changeObject, Name: "Invoice__c"}},
+			want:    []string{"InvoiceTest"},
+		},
+		{
+			name:    "runs a changed test",
+			changes: []change{{Kind: changeClass, Name: "ReportTest"}},
+			want:    []string{"ReportTest"},
+		},
+		{
+			name:    "uses the coverage index for indexed tests",
+			changes: []change{{Kind: changeClass, Name: "AccountService"}, {Kind: changeClass, Name: "InvoiceHandler"}},
+			covered: map[string][]string{"accountcontrollertest": {"AccountController"}, "invoicetest": {"InvoiceHandler", "InvoiceTrigger"}},
+			want:    []string{"InvoiceTest"},
+		},
+		{
+			name:    "scans tests missing from the coverage index",
+			changes: []change{{Kind: changeClass, Name: "AccountService"}},
+			covered: map[string][]string{"invoicetest": {"InvoiceHandler"}},
+			want:    []string{"AccountControllerTest"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := index.affectedTests(tc.changes, tc.covered); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("affectedTests = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// gitProject commits apexProject on main, then applies changes on a branch.
+func gitProject(t *testing.T, changes map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	writeProject(t, dir, apexProject)
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	git("checkout", "-q", "-b", "feature")
+	writeProject(t, dir, changes)
+	git("add", "-A")
+	git("commit", "-q", "-m", "change")
+	return dir
+}
+
+func TestRunOnlyAffectedTests(t *testing.T) {
+	cases := []struct {
+		name      string
+		changes   map[string]string
+		base      string
+		want      []string
+		selection string
+	}{
+		{
+			name:      "passes affected classes",
+			changes:   map[string]string{"force-app/main/default/classes/AccountService.cls": "public class AccountService {}"},
+			base:      "main",
+			want:      []string{"test", "force-app", "-f", "AccountControllerTest", "--junit=r.xml", "--coverage=c.json"},
+			selection: selectedAffected,
+		},
+		{
+			name:      "runs everything when other metadata changes",
+			changes:   map[string]string{"force-app/main/default/flows/Invoice.flow-meta.xml": "<Flow/>"},
+			base:      "main",
+			want:      []string{"test", "force-app", "--junit=r.xml", "--coverage=c.json"},
+			selection: selectedAll,
+		},
+		{
+			name:      "runs everything without the diff base",
+			changes:   map[string]string{"force-app/main/default/classes/AccountService.cls": "public class AccountService {}"},
+			base:      "origin/missing",
+			want:      []string{"test", "force-app", "--junit=r.xml", "--coverage=c.json"},
+			selection: selectedAll,
+		},
+		{
+			name:      "runs everything when project configuration changes",
+			changes:   map[string]string{"sfdx-project.json": "{}", "force-app/main/default/classes/AccountService.cls": "public class AccountService {}"},
+			base:      "main",
+			want:      []string{"test", "force-app", "--junit=r.xml", "--coverage=c.json"},
+			selection: selectedAll,
+		},
+		{
+			name:      "runs everything when .forceignore changes",
+			changes:   map[string]string{".forceignore": "**/jsconfig.json"},
+			base:      "main",
+			want:      []string{"test", "force-app", "--junit=r.xml", "--coverage=c.json"},
+			selection: selectedAll,
+		},
+		{
+			name:      "skips aer when no Apex changed",
+			changes:   map[string]string{"docs/setup.md": "# Setup", "docs/diagram.svg": "<svg/>", "CHANGELOG.txt": "v2", "force-app/main/default/lwc/list/list.js": "export default {}"},
+			base:      "main",
+			selection: selectedNone,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := gitProject(t, tc.changes)
+			record := filepath.Join(t.TempDir(), "args")
+			t.Setenv("FAKE_AER_ARGS", record)
+
+			result, err := run(runConfig{Aer: os.Args[0], WorkDir: dir, Sources: []string{"force-app"},
+				JUnitPath: "r.xml", CoveragePath: "c.json", DiffBase: tc.base})
+			if err != nil || result.ExitCode != 0 {
+				t.Fatalf("run: code=%d err=%v", result.ExitCode, err)
+			}
+			if result.Selection != tc.selection {
+				t.Fatalf("expected selection %q, got %q", tc.selection, result.Selection)
+			}
+			data, err := os.ReadFile(record)
+			if tc.want == nil {
+				if err == nil {
+					t.Fatalf("aer should not run, but ran with %q", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fake aer was not invoked: %v", err)
+			}
+			if got := strings.Split(string(data), "\n"); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"aer/internal/actionlog"
+	"aer/internal/apexsource"
+	"aer/internal/ghenv"
+)
+
+type runConfig struct {
+	Aer              string
+	WorkDir          string
+	Sources          []string
+	Flags            []string
+	DefaultNamespace string
+	Classes          []string
+	Sharded          bool
+	JUnitPath        string
+	CoveragePath     string
+	Retries          int
+	// DiffBase, when set, limits the run to the test classes affected by
+	// the changes since its merge base with HEAD.
+	DiffBase        string
+	DependencyIndex string
+}
+
+func main() {
+	var cfg runConfig
+	var source string
+	var flags string
+	var classes string
+	var recordExitCode bool
+	var debug bool
+
+	flag.StringVar(&cfg.Aer, "aer", "aer", "aer executable to run")
+	flag.StringVar(&cfg.WorkDir, "workdir", "", "directory to run aer in (defaults to the current directory)")
+	flag.StringVar(&source, "source", "", "newline- or space-separated source paths passed to aer test")
+	flag.StringVar(&flags, "flags", "", "additional aer test flags, split using shell quoting rules")
+	flag.StringVar(&cfg.DefaultNamespace, "default-namespace", "", "value for --default-namespace")
+	flag.StringVar(&classes, "classes", "", "space-separated test classes to run (from the shard step)")
+	flag.BoolVar(&cfg.Sharded, "sharded", false, "only run --classes, skipping aer entirely when the shard is empty")
+	flag.StringVar(&cfg.JUnitPath, "junit", "", "path for the JUnit XML results")
+	flag.StringVar(&cfg.CoveragePath, "coverage", "", "path for the coverage JSON")
+	flag.StringVar(&cfg.DiffBase, "diff-base", "", "git ref to diff against; only test classes affected by the changes since its merge base run")
+	flag.StringVar(&cfg.DependencyIndex, "dependency-index", "", "JSON mapping test classes to the classes and triggers they cover, used with --diff-base")
+	flag.IntVar(&cfg.Retries, "retries", 0, "re-run failed test methods up to this many times, reporting those that pass as flaky")
+	flag.BoolVar(&recordExitCode, "record-exit-code", false, "write aer's exit status to the exit-code output and exit zero, leaving the job status to the summary step")
+	flag.BoolVar(&debug, "debug", actionlog.RunnerDebug(), "log debug output (also enabled by RUNNER_DEBUG=1)")
+	flag.Parse()
+	actionlog.SetDebug(debug)
+
+	// aer reads these from the environment; keep them out of the logs even
+	// if a flag or test output echoes them.
+	actionlog.Mask(os.Getenv("AER_LICENSE_KEY"))
+	actionlog.Mask(os.Getenv("GITHUB_TOKEN"))
+
+	cfg.Sources = apexsource.SplitPaths(source)
+	cfg.Classes = strings.Fields(classes)
+
+	var err error
+	cfg.Flags, err = splitShellWords(flags)
+	if err != nil {
+		actionlog.Fatalf("parse flags: %v", err)
+	}
+
+	if cfg.JUnitPath == "" || cfg.CoveragePath == "" {
+		tempDir := os.Getenv("RUNNER_TEMP")
+		if tempDir == "" {
+			tempDir = os.TempDir()
+		}
+		if cfg.JUnitPath == "" {
+			cfg.JUnitPath = filepath.Join(tempDir, "aer-test-results.xml")
+		}
+		if cfg.CoveragePath == "" {
+			cfg.CoveragePath = filepath.Join(tempDir, "aer-coverage.json")
+		}
+	}
+
+	result, err := run(cfg)
+	if err != nil {
+		actionlog.Fatal(err)
+	}
+	if recordExitCode {
+		env := ghenv.FromEnvironment()
+		if err := env.SetOutput("exit-code", strconv.Itoa(result.ExitCode)); err != nil {
+			actionlog.Fatalf("write outputs: %v", err)
+		}
+		if err := env.SetOutput("selection", result.Selection); err != nil {
+			actionlog.Fatalf("write outputs: %v", err)
+		}
+		if result.ExitCode != 0 {
+			actionlog.Infof("aer test exited with status %d; the summary step decides whether the job fails", result.ExitCode)
+		}
+		return
+	}
+	os.Exit(result.ExitCode)
+}
+
+// Values of the selection output, which tells the summary step whether the
+// coverage report covers every test.
+const (
+	selectedAll      = "all"
+	selectedAffected = "affected"
+	selectedNone     = "none"
+)
+
+// runResult is the outcome of the run step.
+type runResult struct {
+	ExitCode int
+	// Selection is selectedAll, selectedAffected when only the tests
+	// affected by the changes ran, or selectedNone when aer was skipped.
+	Selection string
+}
+
+// run executes aer test and returns its exit code. Errors are reserved for
+// problems that prevent aer from running at all.
+func run(cfg runConfig) (runResult, error) {
+	if len(cfg.Sources) == 0 {
+		return runResult{}, errors.New("the source input cannot be empty")
+	}
+	if cfg.Retries < 0 {
+		return runResult{}, errors.New("the retries input cannot be negative")
+	}
+	if err := validateSources(cfg.WorkDir, cfg.Sources); err != nil {
+		return runResult{}, err
+	}
+	if cfg.Sharded && len(cfg.Classes) == 0 {
+		actionlog.Infof("No test classes assigned to this shard; skipping aer test.")
+		return runResult{Selection: selectedNone}, nil
+	}
+	result := runResult{Selection: selectedAll}
+	if cfg.DiffBase != "" {
+		selected, err := selectAffectedTests(cfg)
+		if err != nil {
+			return runResult{}, err
+		}
+		if selected.FullRun != "" {
+			actionlog.Infof("Running all tests: %s", selected.FullRun)
+		} else {
+			cfg.Classes = narrowClasses(cfg, selected.Classes)
+			if len(cfg.Classes) == 0 {
+				actionlog.Infof("No test classes affected by the changes since %s; skipping aer test.", cfg.DiffBase)
+				return runResult{Selection: selectedNone}, nil
+			}
+			actionlog.Infof("Running %d test %s affected by the changes since %s", len(cfg.Classes), pluralClasses(len(cfg.Classes)), cfg.DiffBase)
+			result.Selection = selectedAffected
+		}
+	}
+
+	code, err := runAer(cfg)
+	if err == nil && code != 0 && cfg.Retries > 0 {
+		code, err = retryFailures(cfg, code)
+	}
+	result.ExitCode = code
+	return result, err
+}
+
+// runAer executes aer test once and returns its exit code.
+func runAer(cfg runConfig) (int, error) {
+	args := buildArgs(cfg)
+	actionlog.Infof("Running %s %s", cfg.Aer, strings.Join(quoteArgs(args), " "))
+	if cfg.WorkDir != "" {
+		actionlog.Debugf("working directory: %s", cfg.WorkDir)
+	}
+
+	cmd := exec.Command(cfg.Aer, args...)
+	cmd.Dir = cfg.WorkDir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, fmt.Errorf("run %s: %w", cfg.Aer, err)
+	}
+	return 0, nil
+}
+
+// buildArgs assembles the aer test invocation. Report paths come last so they
+// cannot be overridden accidentally by user flags.
+func buildArgs(cfg runConfig) []string {
+	args := []string{"test"}
+	args = append(args, cfg.Sources...)
+	if cfg.DefaultNamespace != "" {
+		args = append(args, "--default-namespace", cfg.DefaultNamespace)
+	}
+	for _, class := range cfg.Classes {
+		args = append(args, "-f", class)
+	}
+	args = append(args, cfg.Flags...)
+	args = append(args, "--junit="+cfg.JUnitPath, "--coverage="+cfg.CoveragePath)
+	return args
+}
+
+func validateSources(workDir string, sources []string) error {
+	var missing []string
+	for _, source := range sources {
+		path := source
+		if !filepath.IsAbs(path) && workDir != "" {
+			path = filepath.Join(workDir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			missing = append(missing, source)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("source path(s) not found: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// splitShellWords splits s into arguments following POSIX shell quoting:
+// single quotes are literal, double quotes allow backslash escapes of
+// $ ` " \ and newline, and an unquoted backslash escapes the next character.
+// No globbing or variable expansion is performed.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+	inWord := false
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		case r == '\\':
+			inWord = true
+			if i+1 >= len(runes) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			if runes[i] != '\n' {
+				current.WriteRune(runes[i])
+			}
+		case r == '\'':
+			inWord = true
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, errors.New("unterminated single quote")
+			}
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+		case r == '"':
+			inWord = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+		default:
+			inWord = true
+			current.WriteRune(r)
+		}
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+// quoteArgs renders args for logging so each argument is unambiguous.
+func quoteArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return quoted
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestMain lets the test binary stand in for aer: when FAKE_AER_ARGS is set it
+// records its arguments there and exits with FAKE_AER_EXIT. When
+// FAKE_AER_SCRIPT is set it plays a scripted run instead; see fakeAerScript.
+func TestMain(m *testing.M) {
+	if script := os.Getenv("FAKE_AER_SCRIPT"); script != "" {
+		os.Exit(fakeAerScript(script, os.Args[1:]))
+	}
+	if record := os.Getenv("FAKE_AER_ARGS"); record != "" {
+		if err := os.WriteFile(record, []byte(strings.Join(os.Args[1:], "\n")), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(99)
+		}
+		code, _ := strconv.Atoi(os.Getenv("FAKE_AER_EXIT"))
+		os.Exit(code)
+	}
+	os.Exit(m.Run())
+}
+
+func TestSplitShellWords(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"--skip SomeTest", []string{"--skip", "SomeTest"}},
+		{`--filter-path "force app/classes"`, []string{"--filter-path", "force app/classes"}},
+		{`--name 'it''s'`, []string{"--name", "its"}},
+		{`--name 'a "quoted" value'`, []string{"--name", `a "quoted" value`}},
+		{`--glob *Test --x=a\ b`, []string{"--glob", "*Test", "--x=a b"}},
+		{`"say \"hi\" \$HOME \n"`, []string{`say "hi" $HOME \n`}},
+		{"--one\n\t--two  ''", []string{"--one", "--two", ""}},
+	}
+	for _, tc := range cases {
+		got, err := splitShellWords(tc.input)
+		if err != nil {
+			t.Fatalf("splitShellWords(%q): %v", tc.input, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("splitShellWords(%q): expected %q, got %q", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestSplitShellWordsRejectsUnbalancedQuotes(t *testing.T) {
+	for _, input := range []string{`--name 'open`, `--name "open`, `trailing\`} {
+		if _, err := splitShellWords(input); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	args := buildArgs(runConfig{
+		Sources:          []string{"force app", "other"},
+		DefaultNamespace: "ns",
+		Classes:          []string{"ATest", "BTest"},
+		Flags:            []string{"--skip", "Slow Test"},
+		JUnitPath:        "/tmp/r.xml",
+		CoveragePath:     "/tmp/c.json",
+	})
+	want := []string{
+		"test", "force app", "other",
+		"--default-namespace", "ns",
+		"-f", "ATest", "-f", "BTest",
+		"--skip", "Slow Test",
+		"--junit=/tmp/r.xml", "--coverage=/tmp/c.json",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("expected %q, got %q", want, args)
+	}
+}
+
+func TestRunValidatesSources(t *testing.T) {
+	dir := t.TempDir()
+	_, err := run(runConfig{Aer: os.Args[0], WorkDir: dir, Sources: []string{"missing"}})
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("expected missing source error, got %v", err)
+	}
+
+	if _, err := run(runConfig{Aer: os.Args[0], WorkDir: dir}); err == nil {
+		t.Fatal("expected error for empty sources")
+	}
+}
+
+func TestRunPassesArgumentsAndExitCode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "force app"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	record := filepath.Join(t.TempDir(), "args")
+	t.Setenv("FAKE_AER_ARGS", record)
+	t.Setenv("FAKE_AER_EXIT", "3")
+
+	result, err := run(runConfig{
+		Aer:          os.Args[0],
+		WorkDir:      dir,
+		Sources:      []string{"force app"},
+		Flags:        []string{"--skip", "A B"},
+		JUnitPath:    "r.xml",
+		CoveragePath: "c.json",
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Fatalf("expected aer's exit code 3, got %d", result.ExitCode)
+	}
+
+	data, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatalf("fake aer was not invoked: %v", err)
+	}
+	got := strings.Split(string(data), "\n")
+	want := []string{"test", "force app", "--skip", "A B", "--junit=r.xml", "--coverage=c.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRunSkipsEmptyShard(t *testing.T) {
+	dir := t.TempDir()
+	record := filepath.Join(t.TempDir(), "args")
+	t.Setenv("FAKE_AER_ARGS", record)
+
+	result, err := run(runConfig{Aer: os.Args[0], WorkDir: dir, Sources: []string{"."}, Sharded: true})
+	if err != nil || result.ExitCode != 0 || result.Selection != selectedNone {
+		t.Fatalf("expected empty shard to succeed, got %+v err=%v", result, err)
+	}
+	if _, err := os.Stat(record); err == nil {
+		t.Fatal("aer should not run for an empty shard")
+	}
+}
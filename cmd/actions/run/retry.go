@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"aer/internal/actionlog"
+)
+
+// retryFailures re-runs the test methods that failed in cfg.JUnitPath up to
+// cfg.Retries times, passing each one to aer as a -f Class.method filter, and
+// merges the outcome back into the report. A test that passes on a retry
+// keeps its earlier failures as <flakyFailure> or <flakyError> elements, the
+// Maven Surefire convention, so the summary reports it as flaky rather than
+// failed; one that keeps failing records each retry as a <rerunFailure>.
+//
+// It returns 0 when every failed test passed on a retry, and code otherwise,
+// including when aer failed without reporting a failed test.
+func retryFailures(cfg runConfig, code int) (int, error) {
+	report, err := readReport(cfg.JUnitPath)
+	if err != nil {
+		actionlog.Warningf("Not retrying: cannot read test results: %v", err)
+		return code, nil
+	}
+	failing := report.failedTests()
+	if len(failing) == 0 {
+		actionlog.Infof("Not retrying: aer exited with status %d without reporting a failed test", code)
+		return code, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "aer-retry-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var flaky []string
+	for attempt := 1; attempt <= cfg.Retries && len(failing) > 0; attempt++ {
+		actionlog.Infof("Retrying %d failed %s (attempt %d of %d): %s",
+			len(failing), pluralTests(len(failing)), attempt, cfg.Retries, strings.Join(failing, ", "))
+		retry := cfg
+		retry.Classes = failing
+		retry.JUnitPath = filepath.Join(tmpDir, fmt.Sprintf("retry-%d.xml", attempt))
+		retry.CoveragePath = filepath.Join(tmpDir, fmt.Sprintf("retry-%d.json", attempt))
+		if _, err := runAer(retry); err != nil {
+			return 0, err
+		}
+		rerun, err := readReport(retry.JUnitPath)
+		if err != nil {
+			actionlog.Warningf("Stopping retries: cannot read the results of attempt %d: %v", attempt, err)
+			break
+		}
+		var passed []string
+		failing, passed = report.merge(rerun, failing)
+		flaky = append(flaky, passed...)
+	}
+
+	report.recount()
+	if err := report.write(cfg.JUnitPath); err != nil {
+		return 0, fmt.Errorf("write merged test results: %w", err)
+	}
+	if len(flaky) > 0 {
+		actionlog.Warningf("%d %s passed on retry and %s flaky: %s", len(flaky), pluralTests(len(flaky)), isAre(len(flaky)), strings.Join(flaky, ", "))
+	}
+	if len(failing) > 0 {
+		actionlog.Infof("%d %s still failing after %d %s", len(failing), pluralTests(len(failing)), cfg.Retries, pluralRetries(cfg.Retries))
+		return code, nil
+	}
+	return 0, nil
+}
+
+func pluralTests(n int) string {
+	if n == 1 {
+		return "test"
+	}
+	return "tests"
+}
+
+func isAre(n int) string {
+	if n == 1 {
+		return "is"
+	}
+	return "are"
+}
+
+func pluralRetries(n int) string {
+	if n == 1 {
+		return "retry"
+	}
+	return "retries"
+}
+
+// xmlNode is a generic XML element, so a JUnit report can be rewritten
+// without dropping the parts the action does not model.
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []*xmlNode `xml:",any"`
+}
+
+func (n *xmlNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// setAttr updates an attribute the element already has.
+func (n *xmlNode) setAttr(name, value string) {
+	for i, a := range n.Attrs {
+		if a.Name.Local == name {
+			n.Attrs[i].Value = value
+		}
+	}
+}
+
+// testCases returns the <testcase> elements under n in document order.
+func (n *xmlNode) testCases() []*xmlNode {
+	if n.XMLName.Local == "testcase" {
+		return []*xmlNode{n}
+	}
+	var cases []*xmlNode
+	for _, child := range n.Nodes {
+		cases = append(cases, child.testCases()...)
+	}
+	return cases
+}
+
+// outcomes returns the test case's <failure> and <error> elements.
+func (n *xmlNode) outcomes() []*xmlNode {
+	var outcomes []*xmlNode
+	for _, child := range n.Nodes {
+		if child.XMLName.Local == "failure" || child.XMLName.Local == "error" {
+			outcomes = append(outcomes, child)
+		}
+	}
+	return outcomes
+}
+
+func (n *xmlNode) testName() string {
+	if class := n.attr("classname"); class != "" {
+		return class + "." + n.attr("name")
+	}
+	return n.attr("name")
+}
+
+// junitReport is a parsed JUnit XML file.
+type junitReport struct {
+	root *xmlNode
+}
+
+func readReport(path string) (*junitReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root xmlNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &junitReport{root: &root}, nil
+}
+
+// failedTests returns the names of the failed test cases in report order.
+func (r *junitReport) failedTests() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, tc := range r.root.testCases() {
+		if name := tc.testName(); len(tc.outcomes()) > 0 && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// retriedOutcome renames <failure> and <error> for a test that passed on a
+// retry (flaky) or an outcome of a retry that failed again (rerun).
+var retriedOutcome = map[string]map[string]string{
+	"flaky": {"failure": "flakyFailure", "error": "flakyError"},
+	"rerun": {"failure": "rerunFailure", "error": "rerunError"},
+}
+
+// merge applies a retry of the failing tests to the report. It returns the
+// tests still failing, including any the retry did not report, and those
+// that passed.
+func (r *junitReport) merge(rerun *junitReport, failing []string) (stillFailing, passed []string) {
+	retried := make(map[string]*xmlNode)
+	for _, tc := range rerun.root.testCases() {
+		retried[tc.testName()] = tc
+	}
+	original := make(map[string]*xmlNode)
+	for _, tc := range r.root.testCases() {
+		if name := tc.testName(); len(tc.outcomes()) > 0 && original[name] == nil {
+			original[name] = tc
+		}
+	}
+
+	for _, name := range failing {
+		tc, retry := original[name], retried[name]
+		switch {
+		case tc == nil || retry == nil:
+			stillFailing = append(stillFailing, name)
+		case len(retry.outcomes()) == 0:
+			for _, outcome := range tc.outcomes() {
+				outcome.XMLName.Local = retriedOutcome["flaky"][outcome.XMLName.Local]
+			}
+			passed = append(passed, name)
+		default:
+			for _, outcome := range retry.outcomes() {
+				outcome.XMLName.Local = retriedOutcome["rerun"][outcome.XMLName.Local]
+				tc.Nodes = append(tc.Nodes, outcome)
+			}
+			stillFailing = append(stillFailing, name)
+		}
+	}
+	return stillFailing, passed
+}
+
+// recount updates the failures and errors attributes of each suite after
+// flaky tests stopped counting as failed.
+func (r *junitReport) recount() {
+	var count func(n *xmlNode) (failures, errors int)
+	count = func(n *xmlNode) (failures, errors int) {
+		if n.XMLName.Local == "testcase" {
+			for _, outcome := range n.outcomes() {
+				if outcome.XMLName.Local == "error" {
+					return 0, 1
+				}
+			}
+			if len(n.outcomes()) > 0 {
+				return 1, 0
+			}
+			return 0, 0
+		}
+		for _, child := range n.Nodes {
+			f, e := count(child)
+			failures += f
+			errors += e
+		}
+		n.setAttr("failures", strconv.Itoa(failures))
+		n.setAttr("errors", strconv.Itoa(errors))
+		return failures, errors
+	}
+	count(r.root)
+}
+
+func (r *junitReport) write(path string) error {
+	var clean func(n *xmlNode)
+	clean = func(n *xmlNode) {
+		if len(n.Nodes) > 0 && strings.TrimSpace(n.Text) == "" {
+			n.Text = ""
+		}
+		for _, child := range n.Nodes {
+			clean(child)
+		}
+	}
+	clean(r.root)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(r.root); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeAerScript plays invocation n of a scripted aer run from dir: it records
+// its arguments in n.args, copies n.xml to the --junit path and exits with
+// the status in n.exit.
+func fakeAerScript(dir string, args []string) int {
+	countFile := filepath.Join(dir, "count")
+	data, _ := os.ReadFile(countFile)
+	n, _ := strconv.Atoi(string(data))
+	n++
+	if err := os.WriteFile(countFile, []byte(strconv.Itoa(n)), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 99
+	}
+	prefix := filepath.Join(dir, strconv.Itoa(n))
+	if err := os.WriteFile(prefix+".args", []byte(strings.Join(args, "\n")), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 99
+	}
+	for _, arg := range args {
+		if junit, ok := strings.CutPrefix(arg, "--junit="); ok {
+			if results, err := os.ReadFile(prefix + ".xml"); err == nil {
+				if err := os.WriteFile(junit, results, 0o644); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return 99
+				}
+			}
+		}
+	}
+	exit, _ := os.ReadFile(prefix + ".exit")
+	code, _ := strconv.Atoi(strings.TrimSpace(string(exit)))
+	return code
+}
+
+// scriptAer writes the JUnit results and exit status of each aer invocation.
+func scriptAer(t *testing.T, runs ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, results := range runs {
+		prefix := filepath.Join(dir, strconv.Itoa(i+1))
+		code := "0"
+		if strings.Contains(results, "<failure") || strings.Contains(results, "<error") {
+			code = "1"
+		}
+		if err := os.WriteFile(prefix+".xml", []byte(results), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(prefix+".exit", []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("FAKE_AER_SCRIPT", dir)
+	return dir
+}
+
+func invocations(t *testing.T, dir string) [][]string {
+	t.Helper()
+	data, _ := os.ReadFile(filepath.Join(dir, "count"))
+	n, _ := strconv.Atoi(string(data))
+	var calls [][]string
+	for i := 1; i <= n; i++ {
+		args, err := os.ReadFile(filepath.Join(dir, strconv.Itoa(i)+".args"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls = append(calls, strings.Split(string(args), "\n"))
+	}
+	return calls
+}
+
+const firstRun = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="aer" tests="3" failures="2" time="1.5">
+  <testcase classname="AccountTest" name="testName" time="0.5"/>
+  <testcase classname="OrderTest" name="testAsync" time="0.5">
+    <failure message="expected 1, got 0" type="System.AssertException">stack</failure>
+  </testcase>
+  <testcase classname="OrderTest" name="testTotal" time="0.5">
+    <failure message="expected 10, got 9" type="System.AssertException"/>
+    <system-out>total</system-out>
+  </testcase>
+</testsuite>
+`
+
+func TestRunRetriesFailedTests(t *testing.T) {
+	script := scriptAer(t, firstRun,
+		`<testsuite tests="2" failures="1">
+  <testcase classname="OrderTest" name="testAsync"/>
+  <testcase classname="OrderTest" name="testTotal"><failure message="expected 10, got 8"/></testcase>
+</testsuite>`,
+		`<testsuite tests="1" failures="1">
+  <testcase classname="OrderTest" name="testTotal"><failure message="expected 10, got 7"/></testcase>
+</testsuite>`)
+	junit := filepath.Join(t.TempDir(), "results.xml")
+
+	result, err := run(runConfig{Aer: os.Args[0], WorkDir: t.TempDir(), Sources: []string{"."}, Flags: []string{"--verbose"},
+		JUnitPath: junit, CoveragePath: filepath.Join(t.TempDir(), "coverage.json"), Retries: 2})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.ExitCode != 1 {
+		t.Fatalf("expected the original exit code while a test still fails, got %d", result.ExitCode)
+	}
+
+	calls := invocations(t, script)
+	if len(calls) != 3 {
+		t.Fatalf("expected a run and two retries, got %d invocations", len(calls))
+	}
+	if want := []string{"test", ".", "-f", "OrderTest.testAsync", "-f", "OrderTest.testTotal", "--verbose"}; !reflect.DeepEqual(calls[1][:7], want) {
+		t.Fatalf("first retry ran %q, want it to start with %q", calls[1], want)
+	}
+	if want := []string{"test", ".", "-f", "OrderTest.testTotal", "--verbose"}; !reflect.DeepEqual(calls[2][:5], want) {
+		t.Fatalf("second retry ran %q, want it to start with %q", calls[2], want)
+	}
+	if !strings.HasPrefix(calls[1][7], "--junit=") || calls[1][7] == "--junit="+junit {
+		t.Fatalf("retry must write its results elsewhere, got %q", calls[1][7])
+	}
+
+	report, err := readReport(junit)
+	if err != nil {
+		t.Fatalf("read merged results: %v", err)
+	}
+	if failed := report.failedTests(); !reflect.DeepEqual(failed, []string{"OrderTest.testTotal"}) {
+		t.Fatalf("expected only testTotal to still fail, got %v", failed)
+	}
+	if report.root.attr("failures") != "1" || report.root.attr("tests") != "3" {
+		t.Fatalf("suite counts not updated: %v", report.root.Attrs)
+	}
+	data, _ := os.ReadFile(junit)
+	for _, want := range []string{
+		`<flakyFailure message="expected 1, got 0" type="System.AssertException">stack</flakyFailure>`,
+		`<rerunFailure message="expected 10, got 8"></rerunFailure>`,
+		`<rerunFailure message="expected 10, got 7"></rerunFailure>`,
+		`<system-out>total</system-out>`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("merged results lack %s:\n%s", want, data)
+		}
+	}
+}
+
+func TestRunPassesWhenRetriesClearFailures(t *testing.T) {
+	script := scriptAer(t, firstRun, `<testsuite tests="2" failures="0">
+  <testcase classname="OrderTest" name="testAsync"/>
+  <testcase classname="OrderTest" name="testTotal"/>
+</testsuite>`)
+	junit := filepath.Join(t.TempDir(), "results.xml")
+
+	result, err := run(runConfig{Aer: os.Args[0], WorkDir: t.TempDir(), Sources: []string{"."}, JUnitPath: junit, CoveragePath: "c.json", Retries: 3})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("expected flaky tests to pass the run, got code=%d err=%v", result.ExitCode, err)
+	}
+	if n := len(invocations(t, script)); n != 2 {
+		t.Fatalf("expected retries to stop once nothing fails, got %d invocations", n)
+	}
+	report, err := readReport(junit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.failedTests()) != 0 || report.root.attr("failures") != "0" {
+		t.Fatalf("flaky tests still count as failed: %v", report.root.Attrs)
+	}
+}
+
+func TestRunDoesNotRetryWithoutFailedTests(t *testing.T) {
+	script := scriptAer(t, `<testsuite tests="1" failures="0"><testcase classname="A" name="b"/></testsuite>`)
+	if err := os.WriteFile(filepath.Join(script, "1.exit"), []byte("2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := run(runConfig{Aer: os.Args[0], WorkDir: t.TempDir(), Sources: []string{"."},
+		JUnitPath: filepath.Join(t.TempDir(), "results.xml"), CoveragePath: "c.json", Retries: 2})
+	if err != nil || result.ExitCode != 2 {
+		t.Fatalf("expected aer's exit code 2, got code=%d err=%v", result.ExitCode, err)
+	}
+	if n := len(invocations(t, script)); n != 1 {
+		t.Fatalf("expected no retries, got %d invocations", n)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"aer/internal/actionlog"
+	"aer/internal/apexsource"
+	"aer/internal/ghenv"
+)
+
+type junitTestSuite struct {
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Classname string  `xml:"classname,attr"`
+	Time      float64 `xml:"time,attr"`
+}
+
+// testClass is a discovered @IsTest class and its expected duration.
+type testClass struct {
+	Name     string
+	Duration float64
+}
+
+func main() {
+	var source string
+	var projectDir string
+	var index int
+	var count int
+	var history string
+	var debug bool
+
+	flag.StringVar(&source, "source", ".", "source path(s) to scan for @IsTest classes")
+	flag.StringVar(&projectDir, "project-dir", ".", "directory that relative source paths are resolved against")
+	flag.IntVar(&index, "index", 0, "1-based index of this shard")
+	flag.IntVar(&count, "count", 0, "total number of shards")
+	flag.StringVar(&history, "history", "", "optional JUnit XML from a previous run used to balance shards by duration")
+	flag.BoolVar(&debug, "debug", actionlog.RunnerDebug(), "log debug output (also enabled by RUNNER_DEBUG=1)")
+	flag.Parse()
+	actionlog.SetDebug(debug)
+
+	if count < 1 {
+		actionlog.Fatal("--count must be at least 1")
+	}
+	if index < 1 || index > count {
+		actionlog.Fatalf("--index must be between 1 and %d", count)
+	}
+
+	names, err := discoverTestClasses(projectDir, apexsource.SplitPaths(source))
+	if err != nil {
+		actionlog.Fatalf("discover test classes: %v", err)
+	}
+	if len(names) == 0 {
+		actionlog.Fatal("no @IsTest classes found in source paths")
+	}
+
+	durations := map[string]float64{}
+	if history != "" {
+		durations, err = readDurations(history)
+		if err != nil {
+			actionlog.Fatalf("read test history: %v", err)
+		}
+	}
+
+	shards := partition(estimateDurations(names, durations), count)
+	selected := shards[index-1]
+
+	env := ghenv.FromEnvironment()
+	if err := env.SetOutput("classes", strings.Join(selected, " ")); err != nil {
+		actionlog.Fatalf("write outputs: %v", err)
+	}
+	if err := env.SetOutput("count", strconv.Itoa(len(selected))); err != nil {
+		actionlog.Fatalf("write outputs: %v", err)
+	}
+
+	actionlog.Infof("Shard %d/%d runs %d of %d test classes: %s", index, count, len(selected), len(names), strings.Join(selected, ", "))
+}
+
+// discoverTestClasses returns the sorted names of all @IsTest classes under
+// the source paths.
+func discoverTestClasses(projectDir string, sources []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, source := range sources {
+		root := source
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(projectDir, root)
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" || d.Name() == "node_modules" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(path) != ".cls" {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			name := strings.TrimSuffix(d.Name(), ".cls")
+			if apexsource.IsTestClass(apexsource.Strip(string(data))) && !seen[strings.ToLower(name)] {
+				seen[strings.ToLower(name)] = true
+				names = append(names, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %w", source, err)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readDurations sums the recorded test case durations per class.
+func readDurations(filename string) (map[string]float64, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var suite junitTestSuite
+	if err := xml.Unmarshal(data, &suite); err != nil {
+		return nil, err
+	}
+	durations := make(map[string]float64)
+	for _, tc := range suite.TestCases {
+		durations[strings.ToLower(tc.Classname)] += tc.Time
+	}
+	return durations, nil
+}
+
+// estimateDurations assigns each class its historical duration. Classes
+// without history are assumed to take the average of the known classes, or
+// one unit when there is no history at all.
+func estimateDurations(names []string, durations map[string]float64) []testClass {
+	var total float64
+	var known int
+	for _, name := range names {
+		if d, ok := durations[strings.ToLower(name)]; ok {
+			total += d
+			known++
+		}
+	}
+	fallback := 1.0
+	if known > 0 && total > 0 {
+		fallback = total / float64(known)
+	}
+
+	classes := make([]testClass, 0, len(names))
+	for _, name := range names {
+		d, ok := durations[strings.ToLower(name)]
+		if !ok {
+			d = fallback
+		}
+		classes = append(classes, testClass{Name: name, Duration: d})
+	}
+	return classes
+}
+
+// partition spreads classes across count shards using longest-processing-time
+// first scheduling. Ties are broken by name and shard number so every job in
+// the matrix computes the same assignment.
+func partition(classes []testClass, count int) [][]string {
+	sorted := make([]testClass, len(classes))
+	copy(sorted, classes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Duration != sorted[j].Duration {
+			return sorted[i].Duration > sorted[j].Duration
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	shards := make([][]string, count)
+	loads := make([]float64, count)
+	for _, cls := range sorted {
+		target := 0
+		for i := 1; i < count; i++ {
+			if loads[i] < loads[target] {
+				target = i
+			}
+		}
+		shards[target] = append(shards[target], cls.Name)
+		loads[target] += cls.Duration
+	}
+	for _, shard := range shards {
+		sort.Strings(shard)
+	}
+	return shards
+}
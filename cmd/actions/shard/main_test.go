@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"aer/internal/apexsource"
+)
+
+func TestDiscoverTestClasses(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"force-app/classes/AccountTest.cls":   "@IsTest private class AccountTest {}",
+		"force-app/classes/Account.cls":       "public class Account {}",
+		"other app/classes/InvoiceTest.cls":   "@isTest public class InvoiceTest {}",
+		"other app/classes/InvoiceTest.cls-x": "@isTest public class Ignored {}",
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	names, err := discoverTestClasses(root, apexsource.SplitPaths("force-app\nother app"))
+	if err != nil {
+		t.Fatalf("discoverTestClasses: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"AccountTest", "InvoiceTest"}) {
+		t.Fatalf("unexpected test classes: %v", names)
+	}
+}
+
+func TestPartitionWithoutHistoryIsEvenAndDeterministic(t *testing.T) {
+	names := []string{"ATest", "BTest", "CTest", "DTest", "ETest"}
+	first := partition(estimateDurations(names, nil), 2)
+	second := partition(estimateDurations(names, nil), 2)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("partition is not deterministic: %v vs %v", first, second)
+	}
+	if len(first[0]) != 3 || len(first[1]) != 2 {
+		t.Fatalf("expected a 3/2 split, got %v", first)
+	}
+
+	seen := map[string]bool{}
+	for _, shard := range first {
+		for _, name := range shard {
+			if seen[name] {
+				t.Fatalf("%s assigned to more than one shard", name)
+			}
+			seen[name] = true
+		}
+	}
+	if len(seen) != len(names) {
+		t.Fatalf("not every class was assigned: %v", first)
+	}
+}
+
+func TestPartitionBalancesByHistoricalDuration(t *testing.T) {
+	names := []string{"FastTest", "MediumTest", "NewTest", "SlowTest"}
+	durations := map[string]float64{
+		"slowtest":   60,
+		"mediumtest": 30,
+		"fasttest":   10,
+	}
+	shards := partition(estimateDurations(names, durations), 2)
+	// NewTest has no history and is estimated at the 33.3s average.
+	want := [][]string{{"FastTest", "SlowTest"}, {"MediumTest", "NewTest"}}
+	if !reflect.DeepEqual(shards, want) {
+		t.Fatalf("expected %v, got %v", want, shards)
+	}
+}
+
+func TestReadDurationsSumsTestCasesPerClass(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.xml")
+	xml := `<testsuite tests="3">
+  <testcase classname="AccountTest" name="a" time="1.5"/>
+  <testcase classname="AccountTest" name="b" time="2.5"/>
+  <testcase classname="InvoiceTest" name="c" time="0.25"/>
+</testsuite>`
+	if err := os.WriteFile(path, []byte(xml), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	durations, err := readDurations(path)
+	if err != nil {
+		t.Fatalf("readDurations: %v", err)
+	}
+	if durations["accounttest"] != 4 || durations["invoicetest"] != 0.25 {
+		t.Fatalf("unexpected durations: %v", durations)
+	}
+}
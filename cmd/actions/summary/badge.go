@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Shields-style badge colors.
+const (
+	badgeLabelColor  = "#555"
+	badgeColorGreen  = "#4c1"
+	badgeColorYellow = "#dfb317"
+	badgeColorOrange = "#fe7d37"
+	badgeColorRed    = "#e05d44"
+)
+
+// coverageThresholds are the minimum percentages for the good, fair and poor
+// coverage levels. Anything below Poor is considered critical.
+type coverageThresholds struct {
+	Good float64
+	Fair float64
+	Poor float64
+}
+
+var defaultCoverageThresholds = coverageThresholds{Good: 80, Fair: 60, Poor: 40}
+
+// thresholds drives both the summary emoji and the badge colors so the two
+// never disagree. main overrides it from --coverage-thresholds.
+var thresholds = defaultCoverageThresholds
+
+// parseCoverageThresholds parses "good,fair,poor", e.g. "80,60,40".
+func parseCoverageThresholds(value string) (coverageThresholds, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return coverageThresholds{}, fmt.Errorf("expected three comma-separated percentages (good,fair,poor), got %q", value)
+	}
+	var levels [3]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return coverageThresholds{}, fmt.Errorf("invalid threshold %q: %w", part, err)
+		}
+		levels[i] = v
+	}
+	if levels[0] < levels[1] || levels[1] < levels[2] {
+		return coverageThresholds{}, fmt.Errorf("thresholds must be in descending order, got %q", value)
+	}
+	return coverageThresholds{Good: levels[0], Fair: levels[1], Poor: levels[2]}, nil
+}
+
+func getCoverageBadgeColor(percentage float64) string {
+	if percentage >= thresholds.Good {
+		return badgeColorGreen
+	} else if percentage >= thresholds.Fair {
+		return badgeColorYellow
+	} else if percentage >= thresholds.Poor {
+		return badgeColorOrange
+	}
+	return badgeColorRed
+}
+
+func generateCoverageBadge(label string, coverage CoverageSummary) string {
+	percentage := coverage.OverallCoverage
+	return renderBadge(label, fmt.Sprintf("%.0f%%", percentage), getCoverageBadgeColor(percentage))
+}
+
+func generateTestsBadge(label string, suite junitTestSuite) string {
+	passed, failed, _ := suite.outcomes()
+	if failed > 0 {
+		return renderBadge(label, fmt.Sprintf("%d passed, %d failed", passed, failed), badgeColorRed)
+	}
+	return renderBadge(label, fmt.Sprintf("%d passed", passed), badgeColorGreen)
+}
+
+// renderBadge produces a flat, shields.io-compatible SVG badge.
+func renderBadge(label, message, color string) string {
+	labelWidth := textWidth(label) + 10
+	messageWidth := textWidth(message) + 10
+	totalWidth := labelWidth + messageWidth
+
+	labelX := labelWidth * 10 / 2
+	messageX := (labelWidth + messageWidth/2) * 10
+	label = escapeXML(label)
+	message = escapeXML(message)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, totalWidth, label, message))
+	sb.WriteString(fmt.Sprintf(`<title>%s: %s</title>`, label, message))
+	sb.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	sb.WriteString(fmt.Sprintf(`<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, totalWidth))
+	sb.WriteString(`<g clip-path="url(#r)">`)
+	sb.WriteString(fmt.Sprintf(`<rect width="%d" height="20" fill="%s"/>`, labelWidth, badgeLabelColor))
+	sb.WriteString(fmt.Sprintf(`<rect x="%d" width="%d" height="20" fill="%s"/>`, labelWidth, messageWidth, color))
+	sb.WriteString(fmt.Sprintf(`<rect width="%d" height="20" fill="url(#s)"/>`, totalWidth))
+	sb.WriteString(`</g>`)
+	sb.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" text-rendering="geometricPrecision" font-size="110">`)
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="150" fill="#010101" fill-opacity=".3" transform="scale(.1)">%s</text>`, labelX, label))
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="140" transform="scale(.1)">%s</text>`, labelX, label))
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="150" fill="#010101" fill-opacity=".3" transform="scale(.1)">%s</text>`, messageX, message))
+	sb.WriteString(fmt.Sprintf(`<text x="%d" y="140" transform="scale(.1)">%s</text>`, messageX, message))
+	sb.WriteString(`</g></svg>`)
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// textWidth approximates the rendered width of s in 11px Verdana, which is
+// close enough for badge layout without shipping font metrics.
+func textWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("iljI.,:;|!' ", r):
+			width += 4
+		case strings.ContainsRune("frt()[]-", r):
+			width += 5
+		case strings.ContainsRune("mwMW%@", r):
+			width += 11
+		case r >= 'A' && r <= 'Z':
+			width += 8
+		default:
+			width += 7
+		}
+	}
+	return width
+}
+
+func escapeXML(s string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+	return replacer.Replace(s)
+}
+
+func writeBadge(path, svg string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(svg), 0o644)
+}
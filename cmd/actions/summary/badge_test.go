@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestGenerateCoverageBadgeUsesThresholdColors(t *testing.T) {
+	cases := []struct {
+		coverage float64
+		color    string
+	}{
+		{coverage: 92, color: badgeColorGreen},
+		{coverage: 80, color: badgeColorGreen},
+		{coverage: 65, color: badgeColorYellow},
+		{coverage: 45, color: badgeColorOrange},
+		{coverage: 12, color: badgeColorRed},
+	}
+
+	for _, tc := range cases {
+		svg := generateCoverageBadge("coverage", CoverageSummary{OverallCoverage: tc.coverage})
+		if !strings.Contains(svg, `fill="`+tc.color+`"`) {
+			t.Fatalf("coverage %.0f%%: expected color %s in badge: %s", tc.coverage, tc.color, svg)
+		}
+	}
+}
+
+func TestGenerateCoverageBadgeHonorsCustomThresholds(t *testing.T) {
+	custom, err := parseCoverageThresholds("90, 75, 50")
+	if err != nil {
+		t.Fatalf("parse thresholds: %v", err)
+	}
+	thresholds = custom
+	defer func() { thresholds = defaultCoverageThresholds }()
+
+	svg := generateCoverageBadge("apex coverage", CoverageSummary{OverallCoverage: 85})
+	if !strings.Contains(svg, `fill="`+badgeColorYellow+`"`) {
+		t.Fatalf("expected 85%% to be yellow with custom thresholds: %s", svg)
+	}
+	if !strings.Contains(svg, "apex coverage: 85%") {
+		t.Fatalf("badge missing custom label: %s", svg)
+	}
+	if getCoverageEmoji(85) != "🟡" {
+		t.Fatalf("summary emoji should follow the same thresholds as the badge")
+	}
+}
+
+func TestGenerateTestsBadgeReportsFailures(t *testing.T) {
+	passing := generateTestsBadge("tests", junitTestSuite{Tests: 12})
+	if !strings.Contains(passing, "12 passed") || !strings.Contains(passing, badgeColorGreen) {
+		t.Fatalf("unexpected passing badge: %s", passing)
+	}
+
+	failing := generateTestsBadge("tests", junitTestSuite{Tests: 12, Failures: 2})
+	if !strings.Contains(failing, "10 passed, 2 failed") || !strings.Contains(failing, badgeColorRed) {
+		t.Fatalf("unexpected failing badge: %s", failing)
+	}
+
+	errored := generateTestsBadge("tests", junitTestSuite{Tests: 2, TestCases: []junitTestCase{
+		{Classname: "AccountTest", Name: "testName", Errors: []junitFailure{{Type: "System.NullPointerException"}}},
+		{Classname: "AccountTest", Name: "testCreate"},
+	}})
+	if !strings.Contains(errored, "1 passed, 1 failed") || !strings.Contains(errored, badgeColorRed) {
+		t.Fatalf("unexpected badge for an errored test: %s", errored)
+	}
+}
+
+func TestRenderBadgeProducesWellFormedSVG(t *testing.T) {
+	svg := renderBadge(`R&D <tests>`, "1 passed", badgeColorGreen)
+	var doc struct {
+		XMLName xml.Name `xml:"svg"`
+		Title   string   `xml:"title"`
+	}
+	if err := xml.Unmarshal([]byte(svg), &doc); err != nil {
+		t.Fatalf("badge is not valid XML: %v\n%s", err, svg)
+	}
+	if doc.Title != "R&D <tests>: 1 passed" {
+		t.Fatalf("unexpected title %q", doc.Title)
+	}
+}
+
+func TestParseCoverageThresholdsRejectsInvalidInput(t *testing.T) {
+	for _, value := range []string{"80,60", "80,sixty,40", "40,60,80"} {
+		if _, err := parseCoverageThresholds(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// flaky reports whether the test failed at first but passed when retried.
+func (tc junitTestCase) flaky() bool {
+	return !tc.failed() && len(tc.FlakyFailures)+len(tc.FlakyErrors) > 0
+}
+
+func countFlaky(cases []junitTestCase) int {
+	n := 0
+	for _, tc := range cases {
+		if tc.flaky() {
+			n++
+		}
+	}
+	return n
+}
+
+func testStatusEmoji(tc junitTestCase) string {
+	switch {
+	case tc.failed():
+		return "❌"
+	case tc.flaky():
+		return "🔁"
+	default:
+		return "✅"
+	}
+}
+
+// writeFlakySummary lists the tests that passed on retry with the failures
+// they retried past, so intermittent tests can be tracked down without
+// failing the job.
+func writeFlakySummary(sb *strings.Builder, cases []junitTestCase) {
+	sb.WriteString("## 🔁 Flaky Tests\n\n")
+	sb.WriteString("These tests failed, then passed when retried.\n\n")
+	for _, tc := range cases {
+		if !tc.flaky() {
+			continue
+		}
+		attempts := append(append([]junitFailure{}, tc.FlakyFailures...), tc.FlakyErrors...)
+		sb.WriteString(fmt.Sprintf("### %s.%s\n\n", tc.Classname, tc.Name))
+		sb.WriteString(fmt.Sprintf("Passed after %d failed %s.\n\n", len(attempts), plural(len(attempts), "attempt")))
+		for _, f := range attempts {
+			msg := f.Message
+			if msg == "" {
+				msg = f.Body
+			}
+			if msg != "" {
+				sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", strings.TrimSpace(msg)))
+			}
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const unassignedGroup = "(unassigned)"
+
+// GroupResult holds the subtotals for one package directory or CODEOWNERS
+// owner.
+type GroupResult struct {
+	Name         string
+	Tests        int
+	Failures     int
+	TotalLines   int
+	CoveredLines int
+	Coverage     float64
+	Threshold    float64
+	HasThreshold bool
+	Classes      []ClassCoverageInfo
+}
+
+// BelowThreshold reports whether the group has a threshold and its coverage
+// falls short of it.
+func (g GroupResult) BelowThreshold() bool {
+	return g.HasThreshold && g.TotalLines > 0 && g.Coverage < g.Threshold
+}
+
+// groupClassifier maps a project-relative, slash-separated file path to the
+// name of the group that owns it.
+type groupClassifier func(path string) string
+
+// classLocator maps lower-cased Apex class and trigger names to
+// project-relative paths.
+type classLocator map[string]string
+
+func (l classLocator) lookup(className string) (string, bool) {
+	name := strings.ToLower(className)
+	if path, ok := l[name]; ok {
+		return path, true
+	}
+	if idx := strings.Index(name, "."); idx >= 0 {
+		// Inner classes live in their top-level file; namespaced names carry
+		// the namespace as a prefix.
+		if path, ok := l[name[:idx]]; ok {
+			return path, true
+		}
+		if path, ok := l[name[strings.LastIndex(name, ".")+1:]]; ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// locateClasses walks the source paths and indexes every .cls and .trigger
+// file by name.
+func locateClasses(projectDir string, sources []string) (classLocator, error) {
+	locator := make(classLocator)
+	for _, source := range sources {
+		root := source
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(projectDir, root)
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" || d.Name() == "node_modules" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			ext := filepath.Ext(path)
+			if ext != ".cls" && ext != ".trigger" {
+				return nil
+			}
+			rel, err := filepath.Rel(projectDir, path)
+			if err != nil {
+				return err
+			}
+			name := strings.ToLower(strings.TrimSuffix(d.Name(), ext))
+			if _, exists := locator[name]; !exists {
+				locator[name] = filepath.ToSlash(rel)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %w", source, err)
+		}
+	}
+	return locator, nil
+}
+
+type sfdxProject struct {
+	PackageDirectories []struct {
+		Path    string `json:"path"`
+		Package string `json:"package"`
+	} `json:"packageDirectories"`
+}
+
+// packageDirectoryClassifier groups files by the sfdx-project.json package
+// directory that contains them, preferring the package alias when present.
+func packageDirectoryClassifier(projectDir string) (groupClassifier, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, "sfdx-project.json"))
+	if err != nil {
+		return nil, err
+	}
+	var project sfdxProject
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("parse sfdx-project.json: %w", err)
+	}
+	if len(project.PackageDirectories) == 0 {
+		return nil, fmt.Errorf("sfdx-project.json has no packageDirectories")
+	}
+
+	type packageDir struct {
+		prefix string
+		name   string
+	}
+	dirs := make([]packageDir, 0, len(project.PackageDirectories))
+	for _, dir := range project.PackageDirectories {
+		prefix := strings.Trim(filepath.ToSlash(filepath.Clean(dir.Path)), "/")
+		name := dir.Package
+		if name == "" {
+			name = prefix
+		}
+		dirs = append(dirs, packageDir{prefix: prefix, name: name})
+	}
+	// Longest prefix wins so nested package directories are attributed
+	// correctly.
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i].prefix) > len(dirs[j].prefix)
+	})
+
+	return func(path string) string {
+		for _, dir := range dirs {
+			if dir.prefix == "." || path == dir.prefix || strings.HasPrefix(path, dir.prefix+"/") {
+				return dir.name
+			}
+		}
+		return unassignedGroup
+	}, nil
+}
+
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeownersClassifier groups files by their CODEOWNERS owners, looking in the
+// same locations GitHub does.
+func codeownersClassifier(projectDir string) (groupClassifier, error) {
+	var data []byte
+	var err error
+	for _, candidate := range []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"} {
+		data, err = os.ReadFile(filepath.Join(projectDir, candidate))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no CODEOWNERS file found in %s", projectDir)
+	}
+
+	rules, err := parseCodeowners(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return func(path string) string {
+		owners := matchCodeowners(rules, path)
+		if len(owners) == 0 {
+			return unassignedGroup
+		}
+		return strings.Join(owners, " ")
+	}, nil
+}
+
+func parseCodeowners(content string) ([]codeownersRule, error) {
+	var rules []codeownersRule
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		pattern, err := compileCodeownersPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CODEOWNERS pattern %q: %w", fields[0], err)
+		}
+		rules = append(rules, codeownersRule{pattern: pattern, owners: fields[1:]})
+	}
+	return rules, scanner.Err()
+}
+
+// matchCodeowners returns the owners of the last rule matching path, which is
+// how GitHub resolves overlapping CODEOWNERS entries.
+func matchCodeowners(rules []codeownersRule, path string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(path) {
+			return rules[i].owners
+		}
+	}
+	return nil
+}
+
+// compileCodeownersPattern converts a gitignore-style CODEOWNERS pattern into
+// a regular expression matched against slash-separated paths.
+func compileCodeownersPattern(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.Trim(pattern, "/")
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(trimmed, "/")
+
+	var sb strings.Builder
+	if anchored {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(trimmed); i++ {
+		switch {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "/**"):
+			sb.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case trimmed[i] == '*':
+			sb.WriteString("[^/]*")
+		case trimmed[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(trimmed[i : i+1]))
+		}
+	}
+	if dirOnly {
+		sb.WriteString("/.*$")
+	} else if strings.HasSuffix(trimmed, "/*") {
+		// GitHub documents "docs/*" as matching direct children only.
+		sb.WriteString("$")
+	} else {
+		sb.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(sb.String())
+}
+
+// parseGroupThresholds parses "group=percentage" pairs separated by commas or
+// newlines. The group name "*" sets a default for every group.
+func parseGroupThresholds(value string) (map[string]float64, error) {
+	result := make(map[string]float64)
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		idx := strings.LastIndex(entry, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("expected group=percentage, got %q", entry)
+		}
+		pct, err := strconv.ParseFloat(strings.TrimSpace(entry[idx+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentage in %q: %w", entry, err)
+		}
+		result[strings.TrimSpace(entry[:idx])] = pct
+	}
+	return result, nil
+}
+
+// groupResults attributes every test case and covered class to a group and
+// computes per-group subtotals.
+func groupResults(results *TestResults, locator classLocator, classify groupClassifier, groupThresholds map[string]float64) []GroupResult {
+	groups := make(map[string]*GroupResult)
+	groupFor := func(className string) *GroupResult {
+		name := unassignedGroup
+		if path, ok := locator.lookup(className); ok {
+			name = classify(path)
+		}
+		group := groups[name]
+		if group == nil {
+			group = &GroupResult{Name: name}
+			groups[name] = group
+		}
+		return group
+	}
+
+	for _, tc := range results.Suite.TestCases {
+		group := groupFor(tc.Classname)
+		group.Tests++
+		if tc.failed() {
+			group.Failures++
+		}
+	}
+
+	for _, cls := range aggregateCoverageByTopLevel(results.Coverage.Classes) {
+		group := groupFor(cls.ClassName)
+		group.TotalLines += cls.TotalLines
+		group.CoveredLines += cls.CoveredCount
+		group.Classes = append(group.Classes, cls)
+	}
+
+	result := make([]GroupResult, 0, len(groups))
+	for _, group := range groups {
+		if group.TotalLines > 0 {
+			group.Coverage = float64(group.CoveredLines) / float64(group.TotalLines) * 100.0
+		}
+		if pct, ok := groupThresholds[group.Name]; ok {
+			group.Threshold, group.HasThreshold = pct, true
+		} else if pct, ok := groupThresholds["*"]; ok {
+			group.Threshold, group.HasThreshold = pct, true
+		}
+		sort.Slice(group.Classes, func(i, j int) bool {
+			return group.Classes[i].Percentage > group.Classes[j].Percentage
+		})
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		// Keep the catch-all group last.
+		if (result[i].Name == unassignedGroup) != (result[j].Name == unassignedGroup) {
+			return result[j].Name == unassignedGroup
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func writeGroupSummary(sb *strings.Builder, groups []GroupResult) {
+	sb.WriteString("## 🗂️ Results by Group\n\n")
+	sb.WriteString("| Group | Tests | Failed | Coverage | Lines Covered | Threshold |\n")
+	sb.WriteString("|-------|-------|--------|----------|---------------|-----------|\n")
+	for _, group := range groups {
+		coverage := "—"
+		if group.TotalLines > 0 {
+			coverage = fmt.Sprintf("%s %.1f%%", getCoverageEmoji(group.Coverage), group.Coverage)
+		}
+		threshold := "—"
+		if group.HasThreshold {
+			status := "✅"
+			if group.BelowThreshold() {
+				status = "❌"
+			}
+			threshold = fmt.Sprintf("%s %.0f%%", status, group.Threshold)
+		}
+		sb.WriteString(fmt.Sprintf("| `%s` | %d | %d | %s | %d / %d | %s |\n",
+			group.Name, group.Tests, group.Failures, coverage, group.CoveredLines, group.TotalLines, threshold))
+	}
+	sb.WriteString("\n")
+
+	for _, group := range groups {
+		if len(group.Classes) == 0 {
+			continue
+		}
+		sb.WriteString("<details>\n")
+		sb.WriteString(fmt.Sprintf("<summary>%s: %d classes</summary>\n\n", group.Name, len(group.Classes)))
+		sb.WriteString("| Class | Coverage | Lines Covered |\n")
+		sb.WriteString("|-------|----------|---------------|\n")
+		for _, cls := range group.Classes {
+			sb.WriteString(fmt.Sprintf("| `%s` | %s %.1f%% %s | %d / %d |\n",
+				cls.ClassName, getCoverageEmoji(cls.Percentage), cls.Percentage, generateMiniBar(cls.Percentage), cls.CoveredCount, cls.TotalLines))
+		}
+		sb.WriteString("\n</details>\n\n")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aer/internal/apexsource"
+)
+
+func writeProjectFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", rel, err)
+	}
+}
+
+func groupTestProject(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeProjectFile(t, root, "sfdx-project.json", `{
+		"packageDirectories": [
+			{"path": "core", "package": "Core", "default": true},
+			{"path": "billing/"}
+		]
+	}`)
+	writeProjectFile(t, root, ".github/CODEOWNERS", `
+# default owner
+*                @org/platform
+/billing/        @org/billing
+*Test.cls        @org/qa
+`)
+	writeProjectFile(t, root, "core/classes/Account.cls", "")
+	writeProjectFile(t, root, "core/classes/AccountTest.cls", "")
+	writeProjectFile(t, root, "billing/classes/Invoice.cls", "")
+	writeProjectFile(t, root, "billing/triggers/InvoiceTrigger.trigger", "")
+	return root
+}
+
+func groupTestResults() *TestResults {
+	return &TestResults{
+		Suite: junitTestSuite{
+			Tests:    4,
+			Failures: 1,
+			Errors:   1,
+			TestCases: []junitTestCase{
+				{Name: "testCreate", Classname: "AccountTest"},
+				{Name: "testUpdate", Classname: "AccountTest", Failures: []junitFailure{{Message: "boom"}}},
+				{Name: "testDelete", Classname: "AccountTest", Errors: []junitFailure{{Type: "System.NullPointerException"}}},
+				{Name: "testOrphan", Classname: "Unknown"},
+			},
+		},
+		Coverage: CoverageSummary{
+			Classes: []ClassCoverageInfo{
+				{ClassName: "Account", CoveredCount: 9, TotalLines: 10, TopLevelClass: "Account"},
+				{ClassName: "Account.Helper", CoveredCount: 1, TotalLines: 10, TopLevelClass: "Account"},
+				{ClassName: "Invoice", CoveredCount: 3, TotalLines: 10, TopLevelClass: "Invoice"},
+				{ClassName: "InvoiceTrigger", CoveredCount: 5, TotalLines: 10},
+			},
+		},
+	}
+}
+
+func TestBuildGroupsByPackageDirectory(t *testing.T) {
+	root := groupTestProject(t)
+	results := groupTestResults()
+
+	groups, err := buildGroups(results, "package", root, []string{"core", "billing"}, "Core=90,*=50")
+	if err != nil {
+		t.Fatalf("buildGroups: %v", err)
+	}
+
+	byName := make(map[string]GroupResult)
+	for _, group := range groups {
+		byName[group.Name] = group
+	}
+	if len(groups) != 3 || groups[len(groups)-1].Name != unassignedGroup {
+		t.Fatalf("expected Core, billing and a trailing unassigned group, got %+v", groups)
+	}
+
+	core := byName["Core"]
+	if core.Tests != 3 || core.Failures != 2 {
+		t.Fatalf("expected the failed and errored tests in the Core subtotals: %+v", core)
+	}
+	if core.TotalLines != 20 || core.CoveredLines != 10 || core.Coverage != 50 {
+		t.Fatalf("unexpected Core coverage subtotals: %+v", core)
+	}
+	if !core.HasThreshold || core.Threshold != 90 || !core.BelowThreshold() {
+		t.Fatalf("expected Core to miss its 90%% threshold: %+v", core)
+	}
+
+	billing := byName["billing"]
+	if billing.TotalLines != 20 || billing.CoveredLines != 8 {
+		t.Fatalf("unexpected billing coverage subtotals: %+v", billing)
+	}
+	if billing.Threshold != 50 {
+		t.Fatalf("expected billing to inherit the default threshold: %+v", billing)
+	}
+
+	if byName[unassignedGroup].Tests != 1 {
+		t.Fatalf("unlocated test class should land in the unassigned group: %+v", byName[unassignedGroup])
+	}
+}
+
+func TestBuildGroupsByCodeowners(t *testing.T) {
+	root := groupTestProject(t)
+	results := groupTestResults()
+
+	groups, err := buildGroups(results, "codeowners", root, apexsource.SplitPaths("core\nbilling"), "")
+	if err != nil {
+		t.Fatalf("buildGroups: %v", err)
+	}
+
+	byName := make(map[string]GroupResult)
+	for _, group := range groups {
+		byName[group.Name] = group
+	}
+	if qa := byName["@org/qa"]; qa.Tests != 3 || qa.Failures != 2 {
+		t.Fatalf("AccountTest should be owned by the last matching rule: %+v", groups)
+	}
+	if byName["@org/platform"].TotalLines != 20 {
+		t.Fatalf("Account should be owned by the default owner: %+v", groups)
+	}
+	if byName["@org/billing"].TotalLines != 20 {
+		t.Fatalf("billing classes and triggers should be owned by @org/billing: %+v", groups)
+	}
+
+	summary := generateSummary(&TestResults{Suite: results.Suite, Coverage: results.Coverage, Groups: groups})
+	if !strings.Contains(summary, "## 🗂️ Results by Group") {
+		t.Fatalf("summary missing group section: %s", summary)
+	}
+	if !strings.Contains(summary, "| `@org/billing` | 0 | 0 | 🟠 40.0% | 8 / 20 | — |") {
+		t.Fatalf("summary missing billing subtotal row: %s", summary)
+	}
+}
+
+func TestCompileCodeownersPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*", "force-app/classes/A.cls", true},
+		{"*.cls", "force-app/classes/A.cls", true},
+		{"*.cls", "force-app/classes/A.cls-meta.xml", false},
+		{"/force-app/", "force-app/classes/A.cls", true},
+		{"/force-app/", "other/force-app/A.cls", false},
+		{"classes/", "pkg/classes/A.cls", true},
+		{"docs/*", "docs/readme.md", true},
+		{"docs/*", "docs/nested/readme.md", false},
+		{"**/triggers", "a/b/triggers/T.trigger", true},
+		{"/src/**/Test*.cls", "src/a/b/TestFoo.cls", true},
+		{"/src/**/Test*.cls", "lib/a/TestFoo.cls", false},
+	}
+	for _, tc := range cases {
+		re, err := compileCodeownersPattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("compile %q: %v", tc.pattern, err)
+		}
+		if got := re.MatchString(tc.path); got != tc.match {
+			t.Fatalf("pattern %q against %q: expected %v, got %v", tc.pattern, tc.path, tc.match, got)
+		}
+	}
+}
@@ -3,12 +3,17 @@ package main
 import (
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
 	"math"
 	"os"
 	"sort"
 	"strings"
+
+	"aer/internal/actionlog"
+	"aer/internal/apexsource"
+	"aer/internal/ghenv"
 )
 
 // JUnit XML types for parsing test results
@@ -17,15 +22,41 @@ type junitTestSuite struct {
 	Name      string          `xml:"name,attr"`
 	Tests     int             `xml:"tests,attr"`
 	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
 	Time      float64         `xml:"time,attr"`
 	TestCases []junitTestCase `xml:"testcase"`
 }
 
+// outcomes counts the passed, failed and skipped tests. Failed includes
+// tests ending in an <error>. The test cases are counted when the suite lists
+// them, and its failures, errors and skipped attributes otherwise.
+func (s junitTestSuite) outcomes() (passed, failed, skipped int) {
+	if len(s.TestCases) == 0 {
+		failed, skipped = s.Failures+s.Errors, s.Skipped
+	}
+	for _, tc := range s.TestCases {
+		switch {
+		case tc.failed():
+			failed++
+		case tc.Skipped != nil:
+			skipped++
+		}
+	}
+	return max(s.Tests-failed-skipped, 0), failed, skipped
+}
+
 type junitTestCase struct {
 	Name      string         `xml:"name,attr"`
 	Classname string         `xml:"classname,attr"`
 	Time      float64        `xml:"time,attr"`
 	Failures  []junitFailure `xml:"failure"`
+	Errors    []junitFailure `xml:"error"`
+	Skipped   *struct{}      `xml:"skipped"`
+	// FlakyFailures and FlakyErrors record earlier attempts of a test that
+	// passed when the run helper retried it.
+	FlakyFailures []junitFailure `xml:"flakyFailure"`
+	FlakyErrors   []junitFailure `xml:"flakyError"`
 }
 
 type junitFailure struct {
@@ -56,60 +87,189 @@ type CoverageSummary struct {
 type TestResults struct {
 	Suite    junitTestSuite
 	Coverage CoverageSummary
+	Groups   []GroupResult
 }
 
 func main() {
-	junitFile := flag.String("junit", "", "JUnit XML file with test results")
-	coverageFile := flag.String("coverage", "", "JSON file with coverage data")
+	var junitFiles, coverageFiles fileList
+	flag.Var(&junitFiles, "junit", "JUnit XML file with test results (repeat to merge sharded runs)")
+	flag.Var(&coverageFiles, "coverage", "JSON file with coverage data (repeat to merge sharded runs)")
+	coverageOut := flag.String("coverage-out", "", "write the merged coverage JSON to this path")
+	coverageBadge := flag.String("coverage-badge", "", "write a coverage badge SVG to this path")
+	testsBadge := flag.String("tests-badge", "", "write a test results badge SVG to this path")
+	coverageLabel := flag.String("coverage-label", "coverage", "label text for the coverage badge")
+	testsLabel := flag.String("tests-label", "tests", "label text for the test results badge")
+	coverageThresholdsFlag := flag.String("coverage-thresholds", "80,60,40", "minimum coverage percentages for good,fair,poor levels")
+	groupBy := flag.String("group-by", "", "group results by 'package' (sfdx-project.json) or 'codeowners'")
+	groupThresholdsFlag := flag.String("group-thresholds", "", "per-group minimum coverage, e.g. 'core=80,@org/team=75,*=70'")
+	source := flag.String("source", ".", "source path(s) used to locate classes when grouping")
+	projectDir := flag.String("project-dir", ".", "project root containing sfdx-project.json and CODEOWNERS")
+	failOn := flag.String("fail-on", failOnFailures, "conditions that fail the step: failures, errors, coverage, new-failures or none (comma-separated)")
+	minimumCoverage := flag.Float64("minimum-coverage", 75, "overall coverage percentage below which fail-on=coverage fails")
+	knownFailures := flag.String("known-failures", "", "JUnit XML or Class.method list of tests expected to fail, for fail-on=new-failures")
+	exitCode := flag.Int("aer-exit-code", -1, "exit status of aer test, when the run step recorded it")
+	selection := flag.String("selection", "", "tests the run step ran: all, affected or none; fail-on=coverage is not applied unless all ran")
+	debug := flag.Bool("debug", actionlog.RunnerDebug(), "log debug output (also enabled by RUNNER_DEBUG=1)")
 	flag.Parse()
+	actionlog.SetDebug(*debug)
 
-	if *junitFile == "" && *coverageFile == "" {
-		fmt.Fprintf(os.Stderr, "Usage: summary --junit <results.xml> [--coverage <coverage.json>]\n")
-		os.Exit(1)
+	if len(junitFiles) == 0 && len(coverageFiles) == 0 && *exitCode < 0 {
+		actionlog.Fatal("usage: summary --junit <results.xml> [--coverage <coverage.json>] [--coverage-out <merged.json>]")
+	}
+
+	policy, err := parseFailOn(*failOn)
+	if err != nil {
+		actionlog.Fatalf("parse --fail-on: %v", err)
+	}
+	switch *selection {
+	case "", selectionAll, selectionAffected, selectionNone:
+	default:
+		actionlog.Fatalf("unknown --selection %q (want %s, %s or %s)", *selection, selectionAll, selectionAffected, selectionNone)
+	}
+	status := runStatus{
+		ExitCode:        *exitCode,
+		HasJUnit:        len(junitFiles) > 0,
+		HasCoverage:     len(coverageFiles) > 0,
+		MinimumCoverage: *minimumCoverage,
+		Selection:       *selection,
+	}
+	if policy[failOnNewFailures] && *knownFailures != "" {
+		status.KnownFailures, err = readKnownFailures(*knownFailures)
+		if err != nil {
+			actionlog.Fatalf("read known failures: %v", err)
+		}
 	}
 
+	levels, err := parseCoverageThresholds(*coverageThresholdsFlag)
+	if err != nil {
+		actionlog.Fatalf("parse --coverage-thresholds: %v", err)
+	}
+	thresholds = levels
+
 	var results TestResults
 
-	if *junitFile != "" {
-		suite, err := readJUnitXML(*junitFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading JUnit results: %v\n", err)
-			os.Exit(1)
+	if len(junitFiles) > 0 {
+		suites := make([]junitTestSuite, 0, len(junitFiles))
+		for _, junitFile := range junitFiles {
+			suite, err := readJUnitXML(junitFile)
+			if err != nil {
+				actionlog.Fatalf("read JUnit results: %v", err)
+			}
+			suites = append(suites, suite)
 		}
-		results.Suite = suite
+		results.Suite = mergeJUnitSuites(suites)
 	}
 
-	if *coverageFile != "" {
-		cov, err := readCoverageJSON(*coverageFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading coverage data: %v\n", err)
-			os.Exit(1)
+	if len(coverageFiles) > 0 {
+		summaries := make([]CoverageSummary, 0, len(coverageFiles))
+		for _, coverageFile := range coverageFiles {
+			cov, err := readCoverageJSON(coverageFile)
+			if err != nil {
+				actionlog.Fatalf("read coverage data: %v", err)
+			}
+			summaries = append(summaries, cov)
 		}
-		results.Coverage = cov
+		results.Coverage = mergeCoverage(summaries)
 	}
 
-	summary := generateSummary(&results)
+	if *coverageOut != "" {
+		if len(coverageFiles) == 0 {
+			actionlog.Warningf("Skipping --coverage-out: no coverage data")
+		} else if err := writeCoverageJSON(*coverageOut, results.Coverage); err != nil {
+			actionlog.Fatalf("write merged coverage: %v", err)
+		}
+	}
 
-	// Write to GitHub Step Summary
-	summaryFile := os.Getenv("GITHUB_STEP_SUMMARY")
-	if summaryFile != "" {
-		f, err := os.OpenFile(summaryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if *groupBy != "" {
+		groups, err := buildGroups(&results, *groupBy, *projectDir, apexsource.SplitPaths(*source), *groupThresholdsFlag)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening summary file: %v\n", err)
-			os.Exit(1)
+			actionlog.Fatalf("group results: %v", err)
 		}
-		defer f.Close()
+		results.Groups = groups
+	}
 
-		if _, err := f.WriteString(summary); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing summary: %v\n", err)
-			os.Exit(1)
+	if *coverageBadge != "" {
+		if len(coverageFiles) == 0 {
+			actionlog.Warningf("Skipping coverage badge: no coverage data")
+		} else if err := writeBadge(*coverageBadge, generateCoverageBadge(*coverageLabel, results.Coverage)); err != nil {
+			actionlog.Fatalf("write coverage badge: %v", err)
 		}
-		fmt.Println("✅ Generated GitHub Job Summary")
-	} else {
+	}
+
+	if *testsBadge != "" {
+		if len(junitFiles) == 0 {
+			actionlog.Warningf("Skipping tests badge: no JUnit results")
+		} else if err := writeBadge(*testsBadge, generateTestsBadge(*testsLabel, results.Suite)); err != nil {
+			actionlog.Fatalf("write tests badge: %v", err)
+		}
+	}
+
+	env := ghenv.FromEnvironment()
+	files := reportFiles{JUnit: junitFiles, Coverage: coverageFiles}
+	if len(coverageFiles) > 0 {
+		files.CoverageOut = *coverageOut
+	}
+	if err := writeOutputs(env, &results, files); err != nil && !errors.Is(err, ghenv.ErrUnset) {
+		actionlog.Fatalf("%v", err)
+	}
+
+	summary := "⚠️ No test results found\n"
+	switch {
+	case status.HasJUnit || status.HasCoverage:
+		summary = generateSummary(&results)
+	case status.Selection == selectionNone:
+		summary = "ℹ️ aer test was skipped: no tests were selected\n\n"
+	}
+	violations := policy.violations(&results, status)
+	summary += generateCoverageSkippedSummary(policy, status)
+	summary += generatePolicySummary(*failOn, violations)
+
+	// Write to GitHub Step Summary
+	err = env.AppendSummary(summary)
+	switch {
+	case errors.Is(err, ghenv.ErrUnset):
 		fmt.Print(summary)
+	case err != nil:
+		actionlog.Fatalf("write summary: %v", err)
+	default:
+		actionlog.Infof("✅ Generated GitHub Job Summary")
+	}
+
+	for _, violation := range violations {
+		actionlog.Errorf("%s", violation)
+	}
+	if len(violations) > 0 {
+		os.Exit(1)
 	}
 }
 
+func buildGroups(results *TestResults, groupBy, projectDir string, sources []string, thresholdSpec string) ([]GroupResult, error) {
+	var classify groupClassifier
+	var err error
+	switch groupBy {
+	case "package":
+		classify, err = packageDirectoryClassifier(projectDir)
+	case "codeowners":
+		classify, err = codeownersClassifier(projectDir)
+	default:
+		return nil, fmt.Errorf("unsupported --group-by value %q (expected 'package' or 'codeowners')", groupBy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	groupThresholds, err := parseGroupThresholds(thresholdSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	locator, err := locateClasses(projectDir, sources)
+	if err != nil {
+		return nil, err
+	}
+	return groupResults(results, locator, classify, groupThresholds), nil
+}
+
 func readJUnitXML(filename string) (junitTestSuite, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -160,6 +320,9 @@ func generateSummary(results *TestResults) string {
 		sb.WriteString(fmt.Sprintf("| Total Tests | **%d** |\n", suite.Tests))
 		sb.WriteString(fmt.Sprintf("| ✅ Passed | **%d** |\n", passed))
 		sb.WriteString(fmt.Sprintf("| ❌ Failed | **%d** |\n", suite.Failures))
+		if flaky := countFlaky(suite.TestCases); flaky > 0 {
+			sb.WriteString(fmt.Sprintf("| 🔁 Flaky | **%d** |\n", flaky))
+		}
 		sb.WriteString(fmt.Sprintf("| ⏱️ Duration | **%s** |\n", formatDurationSeconds(suite.Time)))
 
 		// Coverage Summary (inline in test summary table)
@@ -209,6 +372,10 @@ func generateSummary(results *TestResults) string {
 		}
 	}
 
+	if len(results.Groups) > 0 {
+		writeGroupSummary(&sb, results.Groups)
+	}
+
 	// Failed tests details
 	if suite.Failures > 0 {
 		sb.WriteString("## ❌ Failed Tests\n\n")
@@ -228,6 +395,10 @@ func generateSummary(results *TestResults) string {
 		}
 	}
 
+	if countFlaky(suite.TestCases) > 0 {
+		writeFlakySummary(&sb, suite.TestCases)
+	}
+
 	// Test timing details
 	if len(suite.TestCases) > 0 {
 		sb.WriteString("## ⏱️ Test Performance\n\n")
@@ -251,10 +422,7 @@ func generateSummary(results *TestResults) string {
 
 		for i := 0; i < maxSlowest; i++ {
 			tc := sortedByDuration[i]
-			statusEmoji := "✅"
-			if len(tc.Failures) > 0 {
-				statusEmoji = "❌"
-			}
+			statusEmoji := testStatusEmoji(tc)
 			sb.WriteString(fmt.Sprintf("| %s `%s.%s` | %s |\n",
 				statusEmoji, tc.Classname, tc.Name, formatDurationSeconds(tc.Time)))
 		}
@@ -271,10 +439,7 @@ func generateSummary(results *TestResults) string {
 		sb.WriteString("|--------|------|----------|\n")
 
 		for _, tc := range suite.TestCases {
-			statusEmoji := "✅"
-			if len(tc.Failures) > 0 {
-				statusEmoji = "❌"
-			}
+			statusEmoji := testStatusEmoji(tc)
 			sb.WriteString(fmt.Sprintf("| %s | `%s.%s` | %s |\n",
 				statusEmoji, tc.Classname, tc.Name, formatDurationSeconds(tc.Time)))
 		}
@@ -299,11 +464,11 @@ func formatDurationSeconds(seconds float64) string {
 }
 
 func getCoverageEmoji(percentage float64) string {
-	if percentage >= 80 {
+	if percentage >= thresholds.Good {
 		return "🟢"
-	} else if percentage >= 60 {
+	} else if percentage >= thresholds.Fair {
 		return "🟡"
-	} else if percentage >= 40 {
+	} else if percentage >= thresholds.Poor {
 		return "🟠"
 	}
 	return "🔴"
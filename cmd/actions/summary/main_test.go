@@ -105,6 +105,42 @@ func TestGenerateSummaryShowsFailedTests(t *testing.T) {
 	}
 }
 
+func TestGenerateSummaryReportsFlakyTestsAsPassed(t *testing.T) {
+	results := &TestResults{
+		Suite: junitTestSuite{
+			Tests: 2,
+			Time:  0.4,
+			TestCases: []junitTestCase{
+				{Name: "testCreate", Classname: "Alpha", Time: 0.1},
+				{
+					Name:          "testAsync",
+					Classname:     "Alpha",
+					Time:          0.3,
+					FlakyFailures: []junitFailure{{Message: "timed out waiting for the queueable"}},
+				},
+			},
+		},
+	}
+
+	summary := generateSummary(results)
+
+	for _, want := range []string{
+		"All Tests Passed",
+		"| 🔁 Flaky | **1** |",
+		"## 🔁 Flaky Tests",
+		"### Alpha.testAsync",
+		"timed out waiting for the queueable",
+		"| 🔁 | `Alpha.testAsync` |",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary lacks %q:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "## ❌ Failed Tests") {
+		t.Fatalf("flaky test reported as failed: %s", summary)
+	}
+}
+
 func TestGenerateSummaryIncludesClassesWhenTopLevelUnknown(t *testing.T) {
 	results := &TestResults{
 		Suite: junitTestSuite{
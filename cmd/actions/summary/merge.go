@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fileList is a repeatable flag that also accepts comma-separated values.
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*f = append(*f, name)
+		}
+	}
+	return nil
+}
+
+// mergeCoverage combines coverage from several partial runs, such as shards of
+// one test suite. A line counts as covered if any run covered it, so the
+// uncovered lines for a class are the intersection of each run's uncovered
+// lines.
+func mergeCoverage(summaries []CoverageSummary) CoverageSummary {
+	if len(summaries) == 1 {
+		return summaries[0]
+	}
+
+	type classState struct {
+		info      ClassCoverageInfo
+		uncovered map[int]bool
+		// exact is false once a run reports coverage without line numbers,
+		// in which case only the best covered count can be trusted.
+		exact      bool
+		maxCovered int
+	}
+
+	states := make(map[string]*classState)
+	var order []string
+	for _, summary := range summaries {
+		for _, cls := range summary.Classes {
+			covered := cls.CoveredCount
+			if covered == 0 && cls.TotalLines > 0 && len(cls.UncoveredLines) > 0 {
+				covered = cls.TotalLines - len(cls.UncoveredLines)
+			}
+			hasLines := len(cls.UncoveredLines) == cls.TotalLines-covered
+
+			state := states[cls.ClassName]
+			if state == nil {
+				state = &classState{info: cls, exact: hasLines, maxCovered: covered}
+				state.uncovered = make(map[int]bool, len(cls.UncoveredLines))
+				for _, line := range cls.UncoveredLines {
+					state.uncovered[line] = true
+				}
+				states[cls.ClassName] = state
+				order = append(order, cls.ClassName)
+				continue
+			}
+
+			if cls.TotalLines > state.info.TotalLines {
+				state.info.TotalLines = cls.TotalLines
+			}
+			if covered > state.maxCovered {
+				state.maxCovered = covered
+			}
+			if !hasLines {
+				state.exact = false
+				continue
+			}
+			stillUncovered := make(map[int]bool, len(cls.UncoveredLines))
+			for _, line := range cls.UncoveredLines {
+				if state.uncovered[line] {
+					stillUncovered[line] = true
+				}
+			}
+			state.uncovered = stillUncovered
+		}
+	}
+
+	merged := CoverageSummary{Classes: make([]ClassCoverageInfo, 0, len(order))}
+	for _, name := range order {
+		state := states[name]
+		cls := state.info
+		if state.exact {
+			cls.UncoveredLines = make([]int, 0, len(state.uncovered))
+			for line := range state.uncovered {
+				cls.UncoveredLines = append(cls.UncoveredLines, line)
+			}
+			sort.Ints(cls.UncoveredLines)
+			cls.UncoveredCount = len(cls.UncoveredLines)
+			cls.CoveredCount = cls.TotalLines - cls.UncoveredCount
+		} else {
+			cls.UncoveredLines = nil
+			cls.CoveredCount = state.maxCovered
+			cls.UncoveredCount = cls.TotalLines - cls.CoveredCount
+		}
+		cls.Percentage = 0
+		if cls.TotalLines > 0 {
+			cls.Percentage = float64(cls.CoveredCount) / float64(cls.TotalLines) * 100.0
+		}
+
+		merged.TotalLines += cls.TotalLines
+		merged.CoveredLines += cls.CoveredCount
+		merged.Classes = append(merged.Classes, cls)
+	}
+	merged.UncoveredLines = merged.TotalLines - merged.CoveredLines
+	if merged.TotalLines > 0 {
+		merged.OverallCoverage = float64(merged.CoveredLines) / float64(merged.TotalLines) * 100.0
+	}
+	return merged
+}
+
+// mergeJUnitSuites concatenates the test cases of several runs into one suite.
+func mergeJUnitSuites(suites []junitTestSuite) junitTestSuite {
+	if len(suites) == 1 {
+		return suites[0]
+	}
+	var merged junitTestSuite
+	for _, suite := range suites {
+		if merged.Name == "" {
+			merged.Name = suite.Name
+		}
+		merged.Tests += suite.Tests
+		merged.Failures += suite.Failures
+		merged.Errors += suite.Errors
+		merged.Skipped += suite.Skipped
+		merged.Time += suite.Time
+		merged.TestCases = append(merged.TestCases, suite.TestCases...)
+	}
+	return merged
+}
+
+func writeCoverageJSON(filename string, coverage CoverageSummary) error {
+	data, err := json.MarshalIndent(coverage, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
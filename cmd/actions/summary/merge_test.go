@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeCoverageUnionsCoveredLines(t *testing.T) {
+	shard1 := CoverageSummary{
+		Classes: []ClassCoverageInfo{
+			{ClassName: "Alpha", TotalLines: 4, CoveredCount: 2, UncoveredLines: []int{3, 4}, TopLevel: true, TopLevelClass: "Alpha"},
+			{ClassName: "Beta", TotalLines: 2, CoveredCount: 0, UncoveredLines: []int{1, 2}},
+		},
+	}
+	shard2 := CoverageSummary{
+		Classes: []ClassCoverageInfo{
+			{ClassName: "Alpha", TotalLines: 4, CoveredCount: 2, UncoveredLines: []int{1, 4}, TopLevel: true, TopLevelClass: "Alpha"},
+			{ClassName: "Beta", TotalLines: 2, CoveredCount: 2},
+			{ClassName: "Gamma", TotalLines: 5, CoveredCount: 5},
+		},
+	}
+
+	merged := mergeCoverage([]CoverageSummary{shard1, shard2})
+
+	classes := make(map[string]ClassCoverageInfo)
+	for _, cls := range merged.Classes {
+		classes[cls.ClassName] = cls
+	}
+
+	alpha := classes["Alpha"]
+	if !reflect.DeepEqual(alpha.UncoveredLines, []int{4}) || alpha.CoveredCount != 3 || alpha.Percentage != 75 {
+		t.Fatalf("unexpected Alpha merge: %+v", alpha)
+	}
+	if !alpha.TopLevel || alpha.TopLevelClass != "Alpha" {
+		t.Fatalf("merge should preserve class metadata: %+v", alpha)
+	}
+
+	beta := classes["Beta"]
+	if len(beta.UncoveredLines) != 0 || beta.CoveredCount != 2 || beta.Percentage != 100 {
+		t.Fatalf("Beta covered in shard 2 should be fully covered: %+v", beta)
+	}
+
+	if merged.TotalLines != 11 || merged.CoveredLines != 10 || merged.UncoveredLines != 1 {
+		t.Fatalf("unexpected merged totals: %+v", merged)
+	}
+	if merged.OverallCoverage < 90.9 || merged.OverallCoverage > 90.91 {
+		t.Fatalf("unexpected overall coverage %.4f", merged.OverallCoverage)
+	}
+}
+
+func TestMergeCoverageFallsBackWithoutLineNumbers(t *testing.T) {
+	merged := mergeCoverage([]CoverageSummary{
+		{Classes: []ClassCoverageInfo{{ClassName: "Alpha", TotalLines: 10, CoveredCount: 4}}},
+		{Classes: []ClassCoverageInfo{{ClassName: "Alpha", TotalLines: 10, CoveredCount: 7, UncoveredLines: []int{1, 2, 3}}}},
+	})
+
+	alpha := merged.Classes[0]
+	if alpha.CoveredCount != 7 || alpha.UncoveredCount != 3 || alpha.UncoveredLines != nil {
+		t.Fatalf("expected best-effort coverage when line numbers are missing: %+v", alpha)
+	}
+}
+
+func TestMergedCoverageRoundTrips(t *testing.T) {
+	merged := mergeCoverage([]CoverageSummary{
+		{Classes: []ClassCoverageInfo{{ClassName: "Alpha", TotalLines: 2, CoveredCount: 1, UncoveredLines: []int{2}}}},
+		{Classes: []ClassCoverageInfo{{ClassName: "Alpha", TotalLines: 2, CoveredCount: 1, UncoveredLines: []int{1}}}},
+	})
+
+	path := filepath.Join(t.TempDir(), "merged", "aer-coverage.json")
+	if err := writeCoverageJSON(path, merged); err != nil {
+		t.Fatalf("write merged coverage: %v", err)
+	}
+	read, err := readCoverageJSON(path)
+	if err != nil {
+		t.Fatalf("read merged coverage: %v", err)
+	}
+	if read.OverallCoverage != 100 || read.CoveredLines != 2 {
+		t.Fatalf("unexpected round-tripped coverage: %+v", read)
+	}
+}
+
+func TestMergeJUnitSuitesCombinesShards(t *testing.T) {
+	merged := mergeJUnitSuites([]junitTestSuite{
+		{Name: "aer", Tests: 2, Failures: 1, Time: 1.5, TestCases: []junitTestCase{{Name: "a"}, {Name: "b"}}},
+		{Name: "aer", Tests: 1, Time: 0.5, TestCases: []junitTestCase{{Name: "c"}}},
+	})
+	if merged.Tests != 3 || merged.Failures != 1 || merged.Time != 2 || len(merged.TestCases) != 3 {
+		t.Fatalf("unexpected merged suite: %+v", merged)
+	}
+}
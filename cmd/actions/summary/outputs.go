@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"aer/internal/ghenv"
+)
+
+// reportFiles are the result files the summary read, exposed as step outputs
+// so later steps can upload them without guessing paths.
+type reportFiles struct {
+	JUnit    []string
+	Coverage []string
+	// CoverageOut is the merged coverage file, when one was written.
+	CoverageOut string
+}
+
+// writeOutputs sets the step outputs describing the run: the test, passed,
+// failure and flaky counts, where failures include tests ending in an error, overall coverage and the report paths. Values that were
+// not measured are written empty so expressions can test for them. Paths are
+// newline-separated when sharded runs are merged.
+func writeOutputs(env ghenv.Env, results *TestResults, files reportFiles) error {
+	var tests, passed, failures, flaky, coverage string
+	if len(files.JUnit) > 0 {
+		passedCount, failedCount, _ := results.Suite.outcomes()
+		tests = strconv.Itoa(results.Suite.Tests)
+		passed = strconv.Itoa(passedCount)
+		failures = strconv.Itoa(failedCount)
+		flaky = strconv.Itoa(countFlaky(results.Suite.TestCases))
+	}
+	if len(files.Coverage) > 0 {
+		coverage = strconv.FormatFloat(results.Coverage.OverallCoverage, 'f', 2, 64)
+	}
+	coveragePath := strings.Join(files.Coverage, "\n")
+	if files.CoverageOut != "" {
+		coveragePath = files.CoverageOut
+	}
+
+	for _, output := range []struct{ name, value string }{
+		{"tests", tests},
+		{"passed", passed},
+		{"failures", failures},
+		{"flaky", flaky},
+		{"coverage", coverage},
+		{"junit-path", strings.Join(files.JUnit, "\n")},
+		{"coverage-path", coveragePath},
+	} {
+		if err := env.SetOutput(output.name, output.value); err != nil {
+			return fmt.Errorf("write %s output: %w", output.name, err)
+		}
+	}
+	return nil
+}
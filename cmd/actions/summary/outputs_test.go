@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"aer/internal/ghenv"
+)
+
+func TestWriteOutputs(t *testing.T) {
+	results := &TestResults{
+		Suite: junitTestSuite{Tests: 5, Failures: 1, Errors: 1, Skipped: 1, TestCases: []junitTestCase{
+			{Classname: "OrderTest", Name: "testCreate"},
+			{Classname: "OrderTest", Name: "testAsync", FlakyFailures: []junitFailure{{Message: "timed out"}}},
+			{Classname: "OrderTest", Name: "testTotal", Failures: []junitFailure{{Type: "System.AssertException"}}},
+			{Classname: "OrderTest", Name: "testTax", Errors: []junitFailure{{Type: "System.NullPointerException"}}},
+			{Classname: "OrderTest", Name: "testLegacy", Skipped: &struct{}{}},
+		}},
+		Coverage: CoverageSummary{OverallCoverage: 81.456},
+	}
+	env := ghenv.NewMemory()
+	err := writeOutputs(env, results, reportFiles{
+		JUnit:       []string{"/tmp/shard-1.xml", "/tmp/shard-2.xml"},
+		Coverage:    []string{"/tmp/shard-1.json", "/tmp/shard-2.json"},
+		CoverageOut: "/tmp/merged.json",
+	})
+	if err != nil {
+		t.Fatalf("writeOutputs: %v", err)
+	}
+	want := map[string]string{
+		"tests":         "5",
+		"passed":        "2",
+		"failures":      "2",
+		"flaky":         "1",
+		"coverage":      "81.46",
+		"junit-path":    "/tmp/shard-1.xml\n/tmp/shard-2.xml",
+		"coverage-path": "/tmp/merged.json",
+	}
+	for name, value := range want {
+		if env.Outputs[name] != value {
+			t.Errorf("output %s = %q, want %q", name, env.Outputs[name], value)
+		}
+	}
+}
+
+func TestWriteOutputsLeavesUnmeasuredValuesEmpty(t *testing.T) {
+	env := ghenv.NewMemory()
+	if err := writeOutputs(env, &TestResults{}, reportFiles{JUnit: []string{"results.xml"}}); err != nil {
+		t.Fatalf("writeOutputs: %v", err)
+	}
+	if env.Outputs["tests"] != "0" || env.Outputs["failures"] != "0" {
+		t.Fatalf("expected zero counts, got %v", env.Outputs)
+	}
+	if value, ok := env.Outputs["coverage"]; !ok || value != "" {
+		t.Fatalf("expected an empty coverage output, got %v", env.Outputs)
+	}
+	if value, ok := env.Outputs["coverage-path"]; !ok || value != "" {
+		t.Fatalf("expected an empty coverage-path output, got %v", env.Outputs)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Conditions accepted by --fail-on.
+const (
+	failOnFailures    = "failures"
+	failOnErrors      = "errors"
+	failOnCoverage    = "coverage"
+	failOnNewFailures = "new-failures"
+	failOnNone        = "none"
+)
+
+// failPolicy is the set of conditions that fail the job.
+type failPolicy map[string]bool
+
+// parseFailOn parses a comma- or space-separated list of conditions. An
+// empty value fails on test failures, as a failing aer test run always has.
+func parseFailOn(value string) (failPolicy, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' })
+	if len(fields) == 0 {
+		return failPolicy{failOnFailures: true}, nil
+	}
+	policy := failPolicy{}
+	for _, field := range fields {
+		switch field = strings.ToLower(field); field {
+		case failOnFailures, failOnErrors, failOnCoverage, failOnNewFailures:
+			policy[field] = true
+		case failOnNone:
+			if len(fields) > 1 {
+				return nil, fmt.Errorf("%q cannot be combined with other conditions", failOnNone)
+			}
+			return failPolicy{}, nil
+		default:
+			return nil, fmt.Errorf("unknown condition %q (want %s, %s, %s, %s or %s)",
+				field, failOnFailures, failOnErrors, failOnCoverage, failOnNewFailures, failOnNone)
+		}
+	}
+	return policy, nil
+}
+
+// Values of the run step's selection output.
+const (
+	selectionAll      = "all"
+	selectionAffected = "affected"
+	selectionNone     = "none"
+)
+
+// runStatus is what the summary knows about the aer test run beyond its
+// reports.
+type runStatus struct {
+	// ExitCode is aer's exit status; -1 when it was not recorded.
+	ExitCode        int
+	HasJUnit        bool
+	HasCoverage     bool
+	MinimumCoverage float64
+	// KnownFailures lists the tests expected to fail, for new-failures.
+	KnownFailures map[string]bool
+	// Selection is which tests the run step ran; empty when not recorded,
+	// which is treated as selectionAll.
+	Selection string
+}
+
+// partial reports whether only some tests ran, or none, so the coverage
+// reported does not describe the code the suite covers.
+func (s runStatus) partial() bool {
+	return s.Selection == selectionAffected || s.Selection == selectionNone
+}
+
+// assertionFailure is the exception a failed System.assert throws. Any other
+// exception ending a test is an error in the test or the code under test.
+const assertionFailure = "System.AssertException"
+
+// testName identifies a test case as Class.method.
+func testName(tc junitTestCase) string {
+	if tc.Classname == "" {
+		return tc.Name
+	}
+	return tc.Classname + "." + tc.Name
+}
+
+// failed reports whether the test failed or errored.
+func (tc junitTestCase) failed() bool {
+	return len(tc.Failures) > 0 || len(tc.Errors) > 0
+}
+
+// errored reports whether the test ended in something other than a failed
+// assertion: a JUnit <error>, or a failure whose type is another exception.
+func (tc junitTestCase) errored() bool {
+	if len(tc.Errors) > 0 {
+		return true
+	}
+	for _, f := range tc.Failures {
+		if f.Type != "" && !strings.EqualFold(f.Type, assertionFailure) {
+			return true
+		}
+	}
+	return false
+}
+
+// violations returns why the policy fails the job, in a stable order, or
+// nil when it passes.
+func (p failPolicy) violations(results *TestResults, status runStatus) []string {
+	var reasons []string
+	var failed, errored, unknown []string
+	for _, tc := range results.Suite.TestCases {
+		if !tc.failed() {
+			continue
+		}
+		name := testName(tc)
+		failed = append(failed, name)
+		if tc.errored() {
+			errored = append(errored, name)
+		}
+		if status.KnownFailures != nil && !status.KnownFailures[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	// aer exiting non-zero without reporting a failed test means the run
+	// itself broke rather than a test.
+	var runError string
+	switch {
+	case status.ExitCode > 0 && !status.HasJUnit:
+		runError = fmt.Sprintf("aer test exited with status %d without writing results", status.ExitCode)
+	case status.ExitCode > 0 && len(failed) == 0 && results.Suite.Failures == 0:
+		runError = fmt.Sprintf("aer test exited with status %d but reported no failed tests", status.ExitCode)
+	}
+	if runError != "" && (p[failOnFailures] || p[failOnErrors] || p[failOnNewFailures]) {
+		reasons = append(reasons, runError)
+	}
+
+	if p[failOnFailures] {
+		switch {
+		case len(failed) > 0:
+			reasons = append(reasons, fmt.Sprintf("%d %s failed: %s", len(failed), plural(len(failed), "test"), listTests(failed)))
+		case results.Suite.Failures > 0:
+			reasons = append(reasons, fmt.Sprintf("%d %s failed", results.Suite.Failures, plural(results.Suite.Failures, "test")))
+		}
+	}
+	if p[failOnErrors] && !p[failOnFailures] && len(errored) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d %s ended in an error other than a failed assertion: %s", len(errored), plural(len(errored), "test"), listTests(errored)))
+	}
+	if p[failOnNewFailures] && !p[failOnFailures] {
+		switch {
+		case status.KnownFailures == nil && len(failed) > 0:
+			reasons = append(reasons, fmt.Sprintf("%d %s failed and no known failures were given: %s", len(failed), plural(len(failed), "test"), listTests(failed)))
+		case len(unknown) > 0:
+			reasons = append(reasons, fmt.Sprintf("%d %s failed that %s not known to fail: %s", len(unknown), plural(len(unknown), "test"), plural(len(unknown), "is", "are"), listTests(unknown)))
+		}
+	}
+
+	if p[failOnCoverage] && !status.partial() {
+		switch {
+		case !status.HasCoverage:
+			reasons = append(reasons, "no coverage data was written")
+		case results.Coverage.OverallCoverage < status.MinimumCoverage:
+			reasons = append(reasons, fmt.Sprintf("coverage %.2f%% is below the minimum of %s%%", results.Coverage.OverallCoverage, formatPercent(status.MinimumCoverage)))
+		}
+		for _, group := range results.Groups {
+			if group.BelowThreshold() {
+				reasons = append(reasons, fmt.Sprintf("%s coverage %.2f%% is below its threshold of %s%%", group.Name, group.Coverage, formatPercent(group.Threshold)))
+			}
+		}
+	}
+	return reasons
+}
+
+// maxListedTests caps the test names included in a violation.
+const maxListedTests = 10
+
+func listTests(names []string) string {
+	sort.Strings(names)
+	if len(names) > maxListedTests {
+		return strings.Join(names[:maxListedTests], ", ") + fmt.Sprintf(" and %d more", len(names)-maxListedTests)
+	}
+	return strings.Join(names, ", ")
+}
+
+func plural(n int, forms ...string) string {
+	if n == 1 {
+		return forms[0]
+	}
+	if len(forms) > 1 {
+		return forms[1]
+	}
+	return forms[0] + "s"
+}
+
+func formatPercent(value float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+}
+
+// readKnownFailures reads the tests expected to fail: either a JUnit XML
+// report, such as one saved from the default branch, whose failed tests are
+// taken, or a text file listing one Class.method per line with # comments.
+func readKnownFailures(filename string) (map[string]bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("<")) {
+		var suite junitTestSuite
+		if err := xml.Unmarshal(trimmed, &suite); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filename, err)
+		}
+		for _, tc := range suite.TestCases {
+			if tc.failed() {
+				known[testName(tc)] = true
+			}
+		}
+		return known, nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			known[line] = true
+		}
+	}
+	return known, scanner.Err()
+}
+
+// generateCoverageSkippedSummary notes in the job summary that fail-on:
+// coverage was not applied because only some tests ran.
+func generateCoverageSkippedSummary(p failPolicy, status runStatus) string {
+	if !p[failOnCoverage] || !status.partial() {
+		return ""
+	}
+	reason := "only the tests affected by the changes ran"
+	if status.Selection == selectionNone {
+		reason = "no tests ran"
+	}
+	return fmt.Sprintf("ℹ️ Coverage was not checked against `fail-on: coverage`: %s, so the coverage reported is partial.\n\n", reason)
+}
+
+// generatePolicySummary explains in the job summary why the job fails.
+func generatePolicySummary(failOn string, violations []string) string {
+	if len(violations) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## 🚦 Failing the job (`fail-on: %s`)\n\n", failOn))
+	for _, violation := range violations {
+		sb.WriteString("- " + violation + "\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
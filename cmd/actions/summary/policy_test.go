@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFailOn(t *testing.T) {
+	cases := []struct {
+		value   string
+		want    failPolicy
+		wantErr string
+	}{
+		{value: "", want: failPolicy{failOnFailures: true}},
+		{value: "failures", want: failPolicy{failOnFailures: true}},
+		{value: "errors, Coverage", want: failPolicy{failOnErrors: true, failOnCoverage: true}},
+		{value: "none", want: failPolicy{}},
+		{value: "none,coverage", wantErr: `"none" cannot be combined`},
+		{value: "flaky", wantErr: `unknown condition "flaky"`},
+	}
+	for _, tc := range cases {
+		got, err := parseFailOn(tc.value)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("parseFailOn(%q): expected error containing %q, got %v", tc.value, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseFailOn(%q) = %v, %v; want %v", tc.value, got, err, tc.want)
+		}
+	}
+}
+
+func TestPolicyViolations(t *testing.T) {
+	assertion := junitTestCase{Classname: "AccountTest", Name: "testName", Failures: []junitFailure{{Type: "System.AssertException"}}}
+	exception := junitTestCase{Classname: "OrderTest", Name: "testTotal", Failures: []junitFailure{{Type: "System.NullPointerException"}}}
+	passed := junitTestCase{Classname: "OrderTest", Name: "testCreate"}
+	results := func(cases ...junitTestCase) *TestResults {
+		r := &TestResults{Coverage: CoverageSummary{OverallCoverage: 80}}
+		r.Suite.TestCases = cases
+		r.Suite.Tests = len(cases)
+		for _, tc := range cases {
+			if tc.failed() {
+				r.Suite.Failures++
+			}
+		}
+		return r
+	}
+	ran := runStatus{ExitCode: 1, HasJUnit: true, HasCoverage: true, MinimumCoverage: 75}
+
+	cases := []struct {
+		name    string
+		failOn  string
+		results *TestResults
+		status  runStatus
+		want    []string
+	}{
+		{
+			name:    "failures fails on any failed test",
+			failOn:  "failures",
+			results: results(assertion, passed),
+			status:  ran,
+			want:    []string{"1 test failed: AccountTest.testName"},
+		},
+		{
+			name:    "errors tolerates failed assertions",
+			failOn:  "errors",
+			results: results(assertion, passed),
+			status:  ran,
+		},
+		{
+			name:    "errors fails on other exceptions",
+			failOn:  "errors",
+			results: results(assertion, exception),
+			status:  ran,
+			want:    []string{"1 test ended in an error other than a failed assertion: OrderTest.testTotal"},
+		},
+		{
+			name:    "errors fails when aer writes no results",
+			failOn:  "errors",
+			results: &TestResults{},
+			status:  runStatus{ExitCode: 2},
+			want:    []string{"aer test exited with status 2 without writing results"},
+		},
+		{
+			name:    "new-failures tolerates known failures",
+			failOn:  "new-failures",
+			results: results(assertion, passed),
+			status:  runStatus{ExitCode: 1, HasJUnit: true, KnownFailures: map[string]bool{"AccountTest.testName": true}},
+		},
+		{
+			name:    "new-failures fails on unknown failures",
+			failOn:  "new-failures",
+			results: results(assertion, exception),
+			status:  runStatus{ExitCode: 1, HasJUnit: true, KnownFailures: map[string]bool{"AccountTest.testName": true}},
+			want:    []string{"1 test failed that is not known to fail: OrderTest.testTotal"},
+		},
+		{
+			name:    "coverage ignores failed tests",
+			failOn:  "coverage",
+			results: results(assertion, exception),
+			status:  ran,
+		},
+		{
+			name:   "coverage fails below the minimum and group thresholds",
+			failOn: "coverage",
+			results: &TestResults{
+				Coverage: CoverageSummary{OverallCoverage: 70.5, TotalLines: 100},
+				Groups: []GroupResult{
+					{Name: "billing", TotalLines: 10, Coverage: 50, Threshold: 80, HasThreshold: true},
+					{Name: "core", TotalLines: 10, Coverage: 90, Threshold: 80, HasThreshold: true},
+				},
+			},
+			status: runStatus{ExitCode: 0, HasJUnit: true, HasCoverage: true, MinimumCoverage: 75},
+			want: []string{
+				"coverage 70.50% is below the minimum of 75%",
+				"billing coverage 50.00% is below its threshold of 80%",
+			},
+		},
+		{
+			name:   "coverage is not checked when only affected tests ran",
+			failOn: "coverage",
+			results: &TestResults{
+				Coverage: CoverageSummary{OverallCoverage: 12, TotalLines: 100},
+				Groups:   []GroupResult{{Name: "billing", TotalLines: 10, Coverage: 0, Threshold: 80, HasThreshold: true}},
+			},
+			status: runStatus{ExitCode: 0, HasJUnit: true, HasCoverage: true, MinimumCoverage: 75, Selection: selectionAffected},
+		},
+		{
+			name:    "coverage is not checked when no tests ran",
+			failOn:  "failures,coverage",
+			results: &TestResults{},
+			status:  runStatus{ExitCode: 0, MinimumCoverage: 75, Selection: selectionNone},
+		},
+		{
+			name:    "coverage is checked when a diff ran every test",
+			failOn:  "coverage",
+			results: &TestResults{},
+			status:  runStatus{ExitCode: 0, HasJUnit: true, MinimumCoverage: 75, Selection: selectionAll},
+			want:    []string{"no coverage data was written"},
+		},
+		{
+			name:    "none never fails",
+			failOn:  "none",
+			results: &TestResults{},
+			status:  runStatus{ExitCode: 3},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := parseFailOn(tc.failOn)
+			if err != nil {
+				t.Fatalf("parseFailOn: %v", err)
+			}
+			if got := policy.violations(tc.results, tc.status); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("violations = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCoverageSkippedSummary(t *testing.T) {
+	coverage := failPolicy{failOnCoverage: true}
+	if got := generateCoverageSkippedSummary(coverage, runStatus{Selection: selectionAll}); got != "" {
+		t.Fatalf("expected no note for a full run, got %q", got)
+	}
+	if got := generateCoverageSkippedSummary(failPolicy{failOnFailures: true}, runStatus{Selection: selectionAffected}); got != "" {
+		t.Fatalf("expected no note without fail-on: coverage, got %q", got)
+	}
+	if got := generateCoverageSkippedSummary(coverage, runStatus{Selection: selectionAffected}); !strings.Contains(got, "only the tests affected by the changes ran") {
+		t.Fatalf("expected the note to explain the partial run, got %q", got)
+	}
+	if got := generateCoverageSkippedSummary(coverage, runStatus{Selection: selectionNone}); !strings.Contains(got, "no tests ran") {
+		t.Fatalf("expected the note to explain the skipped run, got %q", got)
+	}
+}
+
+func TestReadKnownFailures(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "known-failures.txt")
+	if err := os.WriteFile(list, []byte("# flaky in CI\nAccountTest.testName\n\n  OrderTest.testTotal # tracked in #12\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report := filepath.Join(dir, "baseline.xml")
+	junit := `<testsuite tests="2" failures="1">
+  <testcase classname="AccountTest" name="testName"><failure type="System.AssertException"/></testcase>
+  <testcase classname="OrderTest" name="testTotal"/>
+</testsuite>`
+	if err := os.WriteFile(report, []byte(junit), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]map[string]bool{
+		list:   {"AccountTest.testName": true, "OrderTest.testTotal": true},
+		report: {"AccountTest.testName": true},
+	} {
+		got, err := readKnownFailures(path)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("readKnownFailures(%s) = %v, %v; want %v", filepath.Base(path), got, err, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"aer/internal/actionlog"
+	"aer/internal/artifact"
+	"aer/internal/ghenv"
+)
+
+// config holds the upload command's inputs.
+type config struct {
+	Name          string
+	Paths         []string
+	RetentionDays int
+	// RunURL is the workflow run's web page, used to link the artifact.
+	RunURL string
+	Client *artifact.Client
+}
+
+// pathList collects repeated --path flags. Each value may hold several
+// newline-separated paths, as the action's artifact-paths input does.
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, "\n")
+}
+
+func (p *pathList) Set(value string) error {
+	for _, path := range strings.Split(value, "\n") {
+		if path = strings.TrimSpace(path); path != "" {
+			*p = append(*p, path)
+		}
+	}
+	return nil
+}
+
+func main() {
+	var cfg config
+	var paths pathList
+	var debug bool
+
+	cfg.Client = artifact.NewClient()
+	flag.StringVar(&cfg.Name, "name", "aer-reports", "artifact name, unique within the workflow run")
+	flag.Var(&paths, "path", "report file or directory to include (repeatable; missing paths are skipped)")
+	flag.IntVar(&cfg.RetentionDays, "retention-days", 0, "days to keep the artifact (0 keeps the repository default)")
+	flag.StringVar(&cfg.Client.ResultsURL, "results-url", cfg.Client.ResultsURL, "artifact service root (defaults to ACTIONS_RESULTS_URL)")
+	flag.BoolVar(&debug, "debug", actionlog.RunnerDebug(), "log debug output (also enabled by RUNNER_DEBUG=1)")
+	flag.Parse()
+	actionlog.SetDebug(debug)
+
+	cfg.Paths = paths
+	if server, repo, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"); server != "" && repo != "" && runID != "" {
+		cfg.RunURL = fmt.Sprintf("%s/%s/actions/runs/%s", strings.TrimRight(server, "/"), repo, runID)
+	}
+	if err := run(cfg, ghenv.FromEnvironment()); err != nil {
+		actionlog.Fatal(err)
+	}
+}
+
+// run uploads the report files found under cfg.Paths as one artifact and
+// writes its ID and URL as step outputs. Finding no reports is not an error,
+// since aer may have failed before writing any.
+func run(cfg config, env ghenv.Env) error {
+	if err := artifact.ValidateName(cfg.Name); err != nil {
+		return err
+	}
+	if cfg.RetentionDays < 0 {
+		return fmt.Errorf("--retention-days must not be negative, got %d", cfg.RetentionDays)
+	}
+
+	files, err := collectFiles(cfg.Paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		actionlog.Warningf("No reports found to upload as %s", cfg.Name)
+		return nil
+	}
+
+	uploaded, err := cfg.Client.Upload(cfg.Name, files, cfg.RetentionDays)
+	if err != nil {
+		return fmt.Errorf("upload artifact %s: %w", cfg.Name, err)
+	}
+	id := strconv.FormatInt(uploaded.ID, 10)
+	url := ""
+	if cfg.RunURL != "" {
+		url = cfg.RunURL + "/artifacts/" + id
+	}
+	if err := env.SetOutput("artifact-id", id); err != nil {
+		return fmt.Errorf("write outputs: %w", err)
+	}
+	if err := env.SetOutput("artifact-url", url); err != nil {
+		return fmt.Errorf("write outputs: %w", err)
+	}
+	actionlog.Infof("Uploaded %d files (%d bytes) as artifact %s %s", len(files), uploaded.Size, cfg.Name, url)
+	return nil
+}
+
+// collectFiles lists the files to upload. A file is stored under its base
+// name and a directory under its own name, keeping the layout inside it.
+func collectFiles(paths []string) ([]artifact.File, error) {
+	var files []artifact.File
+	seen := make(map[string]string)
+	add := func(name, path string) error {
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("%s and %s would both be stored as %s in the artifact", other, path, name)
+		}
+		seen[name] = path
+		files = append(files, artifact.File{Name: name, Path: path})
+		return nil
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			actionlog.Infof("Skipping %s: not found", path)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if err := add(filepath.Base(path), path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		root := filepath.Clean(path)
+		err = filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			return add(filepath.ToSlash(filepath.Join(filepath.Base(root), rel)), file)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aer/internal/fakeartifacts"
+	"aer/internal/ghenv"
+)
+
+func writeFile(t *testing.T, path, body string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestRunUploadsReports(t *testing.T) {
+	dir := t.TempDir()
+	junit := writeFile(t, filepath.Join(dir, "aer-test-results.xml"), "<testsuite/>")
+	coverage := writeFile(t, filepath.Join(dir, "aer-coverage.json"), "{}")
+	writeFile(t, filepath.Join(dir, "html", "index.html"), "<html></html>")
+	writeFile(t, filepath.Join(dir, "html", "classes", "Foo.html"), "Foo")
+
+	server := fakeartifacts.New(t)
+	env := ghenv.NewMemory()
+	cfg := config{
+		Name:   "aer-reports-2",
+		Paths:  []string{junit, coverage, filepath.Join(dir, "missing.json"), filepath.Join(dir, "html") + "/"},
+		RunURL: "https://github.com/acme/app/actions/runs/42",
+		Client: server.Client(),
+	}
+	if err := run(cfg, env); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	artifacts := server.Artifacts()
+	if len(artifacts) != 1 || artifacts[0].Name != "aer-reports-2" {
+		t.Fatalf("unexpected artifacts %+v", artifacts)
+	}
+	want := []string{"aer-coverage.json", "aer-test-results.xml", "html/classes/Foo.html", "html/index.html"}
+	if got := artifacts[0].Names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("artifact holds %v, want %v", got, want)
+	}
+	if env.Outputs["artifact-id"] != "1001" || env.Outputs["artifact-url"] != "https://github.com/acme/app/actions/runs/42/artifacts/1001" {
+		t.Fatalf("unexpected outputs %v", env.Outputs)
+	}
+}
+
+func TestRunSkipsUploadWithoutReports(t *testing.T) {
+	server := fakeartifacts.New(t)
+	env := ghenv.NewMemory()
+	cfg := config{Name: "aer-reports", Paths: []string{filepath.Join(t.TempDir(), "aer-test-results.xml")}, Client: server.Client()}
+	if err := run(cfg, env); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(server.Artifacts()) != 0 || len(env.Outputs) != 0 {
+		t.Fatalf("expected no upload, got %+v and outputs %v", server.Artifacts(), env.Outputs)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := t.TempDir()
+	junit := writeFile(t, filepath.Join(dir, "a", "results.xml"), "<testsuite/>")
+	other := writeFile(t, filepath.Join(dir, "b", "results.xml"), "<testsuite/>")
+
+	cases := []struct {
+		name    string
+		cfg     config
+		fail    string
+		wantErr string
+	}{
+		{name: "rejects clashing names", cfg: config{Name: "aer-reports", Paths: []string{junit, other}}, wantErr: "would both be stored as results.xml"},
+		{name: "rejects invalid artifact names", cfg: config{Name: "aer:reports", Paths: []string{junit}}, wantErr: `contains ":"`},
+		{name: "reports service failures", cfg: config{Name: "aer-reports", Paths: []string{junit}}, fail: "FinalizeArtifact", wantErr: "upload artifact aer-reports: FinalizeArtifact"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakeartifacts.New(t)
+			if tc.fail != "" {
+				server.Fail(tc.fail, http.StatusInternalServerError)
+			}
+			tc.cfg.Client = server.Client()
+			err := run(tc.cfg, ghenv.NewMemory())
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"aer/internal/release"
+)
+
+type checksumsConfig struct {
+	Dir     string
+	Version string
+	DryRun  bool
+	Out     io.Writer
+}
+
+func checksumsMain(args []string) error {
+	cfg := checksumsConfig{Out: os.Stdout}
+	fs := flag.NewFlagSet("release checksums", flag.ContinueOnError)
+	fs.StringVar(&cfg.Dir, "dir", ".", "directory holding the built release archives")
+	fs.StringVar(&cfg.Version, "version", "", "release version (required)")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "print the checksums without writing any files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return runChecksums(cfg)
+}
+
+// runChecksums writes SHA256SUMS-<version> and manifest-<version>.json for
+// the release archives in cfg.Dir.
+func runChecksums(cfg checksumsConfig) error {
+	if cfg.Version == "" {
+		return errors.New("--version is required")
+	}
+	manifest, err := release.BuildManifest(cfg.Dir, cfg.Version)
+	if err != nil {
+		return err
+	}
+	sums := manifest.SHA256Sums()
+	data, err := manifest.JSON()
+	if err != nil {
+		return err
+	}
+	if cfg.DryRun {
+		fmt.Fprintf(cfg.Out, "would write %s:\n%s", release.ChecksumsName(cfg.Version), sums)
+		fmt.Fprintf(cfg.Out, "would write %s:\n%s", release.ManifestName(cfg.Version), data)
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(cfg.Dir, release.ChecksumsName(cfg.Version)), sums, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(cfg.Dir, release.ManifestName(cfg.Version)), data, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.Out, "Wrote %s and %s for %d archives\n", release.ChecksumsName(cfg.Version), release.ManifestName(cfg.Version), len(manifest.Assets))
+	return nil
+}
@@ -0,0 +1,131 @@
+// Command release cuts and publishes aer-dist releases:
+//
+//	go run ./cmd/release tag         bump aer to the next upstream tag, commit and tag
+//	go run ./cmd/release sbom        write CycloneDX and SPDX SBOMs for each archive
+//	go run ./cmd/release checksums   write SHA256SUMS-<version> and manifest-<version>.json
+//	go run ./cmd/release publish     push the tag and create the GitHub release
+//	go run ./cmd/release verify      rebuild a published binary and compare digests
+//
+// The subcommands that change something accept --dry-run to print what they
+// would do without touching the repository, the module files or GitHub.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const usage = `usage: release <command> [flags]
+
+commands:
+  tag        update aer to the next upstream tag, commit and tag the release
+  sbom       write CycloneDX and SPDX SBOMs for the binary in each built archive
+  checksums  write SHA256SUMS-<version> and manifest-<version>.json for built archives
+  publish    push the release tag and create the GitHub release with its assets
+  verify     rebuild a published binary from its tag and check it is identical
+
+Run 'release <command> -h' for the flags of each command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "tag":
+		err = tagMain(args)
+	case "sbom":
+		err = sbomMain(args)
+	case "checksums":
+		err = checksumsMain(args)
+	case "publish":
+		err = publishMain(args)
+	case "verify":
+		err = verifyMain(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "release: unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "release: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runner executes the git, go and gh commands a release step needs in one
+// directory. Commands that change something go through run, which only
+// prints them in dry-run mode; read-only queries go through output and always
+// run so a dry run reports what would really happen.
+type runner struct {
+	Dir    string
+	DryRun bool
+	Out    io.Writer
+}
+
+// run executes a command that modifies the repository or publishes
+// something, streaming its output.
+func (r *runner) run(name string, args ...string) error {
+	if r.DryRun {
+		fmt.Fprintf(r.Out, "would run: %s\n", formatCommand(name, args))
+		return nil
+	}
+	fmt.Fprintf(r.Out, "+ %s\n", formatCommand(name, args))
+	cmd := exec.Command(name, args...)
+	cmd.Dir = r.Dir
+	cmd.Stdout = r.Out
+	cmd.Stderr = r.Out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", formatCommand(name, args), err)
+	}
+	return nil
+}
+
+// output executes a read-only command and returns its trimmed stdout.
+func (r *runner) output(name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Dir = r.Dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w\n%s", formatCommand(name, args), err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", formatCommand(name, args), err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// succeeds runs a read-only command and reports whether it exited zero, for
+// checks such as `git diff --quiet`.
+func (r *runner) succeeds(name string, args ...string) bool {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = r.Dir
+	return cmd.Run() == nil
+}
+
+func formatCommand(name string, args []string) string {
+	parts := []string{name}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"$") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
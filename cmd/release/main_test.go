@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const testModule = "example.com/aer"
+
+// gitEnv isolates the tests from the user's git configuration and gives
+// commits a fixed identity.
+func gitEnv(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Release Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "release@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Release Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "release@example.com")
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// newUpstream creates a bare repository standing in for the aer repository,
+// with an annotated tag per version.
+func newUpstream(t *testing.T, tags ...string) string {
+	t.Helper()
+	work := t.TempDir()
+	git(t, work, "init", "-q")
+	writeFile(t, filepath.Join(work, "README"), "aer\n")
+	git(t, work, "add", "README")
+	git(t, work, "commit", "-q", "-m", "initial")
+	for _, tag := range tags {
+		git(t, work, "commit", "-q", "--allow-empty", "-m", "release "+tag)
+		git(t, work, "tag", "-a", tag, "-m", "aer "+tag+"\n\nRelease notes for "+tag)
+	}
+	bare := filepath.Join(t.TempDir(), "aer.git")
+	git(t, work, "clone", "-q", "--bare", work, bare)
+	return bare
+}
+
+// newDist creates an aer-dist checkout requiring testModule at version.
+func newDist(t *testing.T, version string) string {
+	t.Helper()
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	writeFile(t, filepath.Join(dir, "go.mod"), "module aer\n\ngo 1.22\n\nrequire (\n\t"+testModule+" "+version+"\n)\n")
+	writeFile(t, filepath.Join(dir, defaultWorkflow), "env:\n  AER_VERSION: "+version+"\n\njobs:\n  test:\n    steps:\n      - run: |\n          echo AER_VERSION: unchanged\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+// fakeGo stands in for the go command: `go get module@version` rewrites the
+// requirement in go.mod and everything else succeeds. Every invocation is
+// appended to the returned log file.
+func fakeGo(t *testing.T) (path, log string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go uses a shell script")
+	}
+	dir := t.TempDir()
+	log = filepath.Join(dir, "calls")
+	script := `#!/bin/sh
+echo "$*" >> "` + log + `"
+if [ "$1" = get ]; then
+	version="${2#*@}"
+	sed -e "s|` + testModule + ` .*|` + testModule + ` $version|" go.mod > go.mod.new && mv go.mod.new go.mod
+	echo "` + testModule + ` $version h1:fake=" > go.sum
+fi
+`
+	path = filepath.Join(dir, "go")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake go: %v", err)
+	}
+	return path, log
+}
+
+func TestRunTag(t *testing.T) {
+	gitEnv(t)
+	upstream := newUpstream(t, "v0.0.9", "v0.0.10", "v0.0.11-rc.1", "v0.0.11", "not-a-version")
+	dist := newDist(t, "v0.0.9")
+	goCmd, calls := fakeGo(t)
+
+	var out bytes.Buffer
+	err := runTag(tagConfig{
+		Dir:      dist,
+		Module:   testModule,
+		Upstream: upstream,
+		Workflow: defaultWorkflow,
+		Go:       goCmd,
+		Out:      &out,
+	})
+	if err != nil {
+		t.Fatalf("runTag: %v\n%s", err, out.String())
+	}
+
+	// v0.0.10 follows v0.0.9 by semver even though it sorts before it as text.
+	if got := git(t, dist, "describe", "--tags", "--exact-match"); got != "v0.0.10" {
+		t.Fatalf("expected HEAD tagged v0.0.10, got %q", got)
+	}
+	if got := git(t, dist, "for-each-ref", "--format=%(contents)", "refs/tags/v0.0.10"); got != "aer v0.0.10\n\nRelease notes for v0.0.10" {
+		t.Fatalf("tag message not copied from upstream: %q", got)
+	}
+	if got := git(t, dist, "log", "-1", "--format=%s"); got != "Update aer to v0.0.10" {
+		t.Fatalf("unexpected commit subject %q", got)
+	}
+	if got := git(t, dist, "status", "--porcelain"); got != "" {
+		t.Fatalf("expected a clean tree after tagging, got:\n%s", got)
+	}
+	if files := git(t, dist, "show", "--name-only", "--format=", "HEAD"); files != ".github/workflows/oss-tests.yml\ngo.mod\ngo.sum" {
+		t.Fatalf("unexpected files in release commit:\n%s", files)
+	}
+	workflow, _ := os.ReadFile(filepath.Join(dist, defaultWorkflow))
+	if !strings.Contains(string(workflow), "  AER_VERSION: v0.0.10\n") || !strings.Contains(string(workflow), "echo AER_VERSION: unchanged") {
+		t.Fatalf("unexpected workflow:\n%s", workflow)
+	}
+	log, _ := os.ReadFile(calls)
+	if string(log) != "get "+testModule+"@v0.0.10\nmod tidy\nbuild ./...\n" {
+		t.Fatalf("unexpected go invocations:\n%s", log)
+	}
+}
+
+func TestRunTagDryRunChangesNothing(t *testing.T) {
+	gitEnv(t)
+	upstream := newUpstream(t, "v0.0.9", "v0.0.10")
+	dist := newDist(t, "v0.0.9")
+	goCmd, calls := fakeGo(t)
+	head := git(t, dist, "rev-parse", "HEAD")
+
+	var out bytes.Buffer
+	err := runTag(tagConfig{
+		Dir:      dist,
+		Module:   testModule,
+		Upstream: upstream,
+		Workflow: defaultWorkflow,
+		DryRun:   true,
+		Sign:     true,
+		Go:       goCmd,
+		Out:      &out,
+	})
+	if err != nil {
+		t.Fatalf("runTag: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{
+		"would run: " + goCmd + " get " + testModule + "@v0.0.10",
+		"would set AER_VERSION in .github/workflows/oss-tests.yml: v0.0.9 -> v0.0.10",
+		"would run: git tag -s v0.0.10 -m 'aer v0.0.10",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("dry run output missing %q:\n%s", want, out.String())
+		}
+	}
+	if got := git(t, dist, "rev-parse", "HEAD"); got != head {
+		t.Fatal("dry run created a commit")
+	}
+	if got := git(t, dist, "tag"); got != "" {
+		t.Fatalf("dry run created tags: %s", got)
+	}
+	if got := git(t, dist, "status", "--porcelain"); got != "" {
+		t.Fatalf("dry run modified the tree:\n%s", got)
+	}
+	if _, err := os.Stat(calls); !os.IsNotExist(err) {
+		t.Fatal("dry run invoked go")
+	}
+}
+
+func TestRunTagRefusesWhenUpToDateOrDirty(t *testing.T) {
+	gitEnv(t)
+	upstream := newUpstream(t, "v0.0.9", "v0.0.10")
+	goCmd, _ := fakeGo(t)
+
+	dist := newDist(t, "v0.0.10")
+	err := runTag(tagConfig{Dir: dist, Module: testModule, Upstream: upstream, Workflow: defaultWorkflow, Go: goCmd, Out: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "already on the latest aer tag") {
+		t.Fatalf("expected up-to-date error, got %v", err)
+	}
+
+	dist = newDist(t, "v0.0.9")
+	writeFile(t, filepath.Join(dist, "go.mod"), "module aer\n")
+	err = runTag(tagConfig{Dir: dist, Module: testModule, Upstream: upstream, Workflow: defaultWorkflow, Go: goCmd, Out: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "working tree must be clean") {
+		t.Fatalf("expected dirty tree error, got %v", err)
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.10.0", "v1.2.0", "v1.2.0-rc.1", "latest"}
+	tests := []struct {
+		current    string
+		prerelease bool
+		want       string
+	}{
+		{"v1.0.0", false, "v1.2.0"},
+		{"v1.0.0", true, "v1.2.0-rc.1"},
+		{"v1.2.0", false, "v1.10.0"},
+		{"v1.1.0-0.20240101000000-abcdef123456", false, "v1.2.0"},
+	}
+	for _, tt := range tests {
+		got, err := nextVersion(tags, tt.current, tt.prerelease)
+		if err != nil || got != tt.want {
+			t.Fatalf("nextVersion(%s, %v) = %q, %v; want %q", tt.current, tt.prerelease, got, err, tt.want)
+		}
+	}
+	if _, err := nextVersion(tags, "v1.10.0", true); err == nil {
+		t.Fatal("expected an error when already on the latest tag")
+	}
+}
+
+func TestRunPublish(t *testing.T) {
+	gitEnv(t)
+	dist := newDist(t, "v1.0.0")
+	git(t, dist, "tag", "-a", "v1.0.0", "-m", "aer v1.0.0")
+	writeFile(t, filepath.Join(dist, "aer_linux_amd64_v1.0.0.zip"), "linux")
+	writeFile(t, filepath.Join(dist, "aer_darwin_arm64_v1.0.0.zip"), "darwin")
+
+	var out bytes.Buffer
+	if err := runChecksums(checksumsConfig{Dir: dist, Version: "v1.0.0", Out: &out}); err != nil {
+		t.Fatalf("runChecksums: %v", err)
+	}
+	writeFile(t, filepath.Join(dist, "SHA256SUMS-v1.0.0.minisig"), "signature")
+
+	out.Reset()
+	cfg := publishConfig{Dir: dist, AssetDir: dist, Version: "v1.0.0", Remote: "origin", DryRun: true, GH: "gh", Out: &out}
+	if err := runPublish(cfg); err != nil {
+		t.Fatalf("runPublish: %v", err)
+	}
+	want := "would run: git push origin refs/tags/v1.0.0\n" +
+		"would run: gh release create v1.0.0 --title 'aer v1.0.0' --notes-from-tag --verify-tag " +
+		strings.Join([]string{
+			filepath.Join(dist, "aer_darwin_arm64_v1.0.0.zip"),
+			filepath.Join(dist, "aer_linux_amd64_v1.0.0.zip"),
+			filepath.Join(dist, "SHA256SUMS-v1.0.0"),
+			filepath.Join(dist, "manifest-v1.0.0.json"),
+			filepath.Join(dist, "SHA256SUMS-v1.0.0.minisig"),
+		}, " ") + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected publish plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	// An archive rebuilt after the checksums were written must not be
+	// uploaded.
+	writeFile(t, filepath.Join(dist, "aer_linux_amd64_v1.0.0.zip"), "rebuilt")
+	if err := runPublish(cfg); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+
+	cfg.Version = "v2.0.0"
+	if err := runPublish(cfg); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected missing tag error, got %v", err)
+	}
+}
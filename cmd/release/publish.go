@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"aer/internal/release"
+)
+
+type publishConfig struct {
+	Dir      string
+	AssetDir string
+	Version  string
+	Remote   string
+	DryRun   bool
+	GH       string
+	Out      io.Writer
+}
+
+func publishMain(args []string) error {
+	cfg := publishConfig{Out: os.Stdout}
+	fs := flag.NewFlagSet("release publish", flag.ContinueOnError)
+	fs.StringVar(&cfg.Dir, "dir", ".", "aer-dist checkout holding the release tag")
+	fs.StringVar(&cfg.AssetDir, "assets", "", "directory holding the release assets (defaults to --dir)")
+	fs.StringVar(&cfg.Version, "version", "", "release tag to publish (required)")
+	fs.StringVar(&cfg.Remote, "remote", "origin", "git remote the tag is pushed to")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "check the release and print the commands without running them")
+	fs.StringVar(&cfg.GH, "gh", "gh", "GitHub CLI used to create the release")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.AssetDir == "" {
+		cfg.AssetDir = cfg.Dir
+	}
+	return runPublish(cfg)
+}
+
+// runPublish checks that the tag and its assets are complete and consistent,
+// then pushes the tag and creates the GitHub release.
+func runPublish(cfg publishConfig) error {
+	if _, err := release.ParseVersion(cfg.Version); err != nil {
+		return fmt.Errorf("--version: %w", err)
+	}
+	git := &runner{Dir: cfg.Dir, DryRun: cfg.DryRun, Out: cfg.Out}
+	if !git.succeeds("git", "rev-parse", "--verify", "--quiet", "refs/tags/"+cfg.Version) {
+		return fmt.Errorf("tag %s does not exist; create it with `go run ./cmd/release tag` first", cfg.Version)
+	}
+
+	assets, err := releaseAssets(cfg.AssetDir, cfg.Version)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath(cfg.GH); err != nil && !cfg.DryRun {
+		return fmt.Errorf("the GitHub CLI (%s) is required to publish: %w", cfg.GH, err)
+	}
+
+	if err := git.run("git", "push", cfg.Remote, "refs/tags/"+cfg.Version); err != nil {
+		return err
+	}
+	args := []string{"release", "create", cfg.Version, "--title", "aer " + cfg.Version, "--notes-from-tag", "--verify-tag"}
+	return git.run(cfg.GH, append(args, assets...)...)
+}
+
+// releaseAssets lists the files to upload for version: the archives and
+// their SBOMs, the checksums, the manifest and any checksums signature. Every
+// file must match the published checksums so a stale build is never
+// uploaded.
+func releaseAssets(dir, version string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, release.ManifestName(version)))
+	if err != nil {
+		return nil, fmt.Errorf("%w; run `go run ./cmd/release checksums --version %s` first", err, version)
+	}
+	manifest, err := release.ParseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Version != version {
+		return nil, fmt.Errorf("%s describes %s, not %s", release.ManifestName(version), manifest.Version, version)
+	}
+	sumsData, err := os.ReadFile(filepath.Join(dir, release.ChecksumsName(version)))
+	if err != nil {
+		return nil, err
+	}
+	sums, err := release.ParseChecksums(sumsData)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []string
+	for _, asset := range manifest.Assets {
+		path := filepath.Join(dir, asset.Name)
+		if sums[asset.Name] != asset.SHA256 {
+			return nil, fmt.Errorf("%s and %s disagree about %s", release.ManifestName(version), release.ChecksumsName(version), asset.Name)
+		}
+		if err := sums.Verify(path, asset.Name); err != nil {
+			return nil, err
+		}
+		assets = append(assets, path)
+		for _, s := range asset.SBOMs {
+			if err := sums.Verify(filepath.Join(dir, s.Name), s.Name); err != nil {
+				return nil, err
+			}
+			assets = append(assets, filepath.Join(dir, s.Name))
+		}
+	}
+	assets = append(assets,
+		filepath.Join(dir, release.ChecksumsName(version)),
+		filepath.Join(dir, release.ManifestName(version)))
+	for _, ext := range []string{".minisig", ".sig"} {
+		signature := filepath.Join(dir, release.ChecksumsName(version)+ext)
+		if _, err := os.Stat(signature); err == nil {
+			assets = append(assets, signature)
+		}
+	}
+	return assets, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"debug/buildinfo"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"aer/internal/installer"
+	"aer/internal/release"
+	"aer/internal/sbom"
+)
+
+// distPURL is the package URL of the aer binaries aer-dist publishes. The
+// main module is the wrapper in this repository, not upstream aer.
+const distPURL = "pkg:github/octoberswimmer/aer-dist@"
+
+type sbomConfig struct {
+	Dir     string
+	Version string
+	DryRun  bool
+	Out     io.Writer
+}
+
+func sbomMain(args []string) error {
+	cfg := sbomConfig{Out: os.Stdout}
+	fs := flag.NewFlagSet("release sbom", flag.ContinueOnError)
+	fs.StringVar(&cfg.Dir, "dir", ".", "directory holding the built release archives")
+	fs.StringVar(&cfg.Version, "version", "", "release version (required)")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "list the SBOMs without writing any files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return runSBOM(cfg)
+}
+
+// runSBOM writes a CycloneDX and an SPDX document next to each release
+// archive in cfg.Dir, describing the modules linked into the binary it
+// holds. Run it before checksums so the manifest lists the SBOMs.
+func runSBOM(cfg sbomConfig) error {
+	if cfg.Version == "" {
+		return errors.New("--version is required")
+	}
+	manifest, err := release.BuildManifest(cfg.Dir, cfg.Version)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "aer-sbom-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, asset := range manifest.Assets {
+		binary, err := installer.ExtractBinary(filepath.Join(cfg.Dir, asset.Name), asset.Binary, filepath.Join(tmpDir, asset.Platform))
+		if err != nil {
+			return fmt.Errorf("extract %s: %w", asset.Name, err)
+		}
+		info, err := buildinfo.ReadFile(binary)
+		if err != nil {
+			return fmt.Errorf("read build information from %s: %w", asset.Name, err)
+		}
+		digest, err := release.FileSHA256(binary)
+		if err != nil {
+			return err
+		}
+		b := sbom.Binary{
+			Name:     "aer",
+			Version:  cfg.Version,
+			PURL:     distPURL + cfg.Version,
+			Platform: asset.Platform,
+			SHA256:   digest,
+			Info:     info,
+		}
+		for _, format := range sbom.Formats {
+			ext, _ := sbom.Extension(format)
+			name := release.SBOMName(asset.Platform, cfg.Version, ext)
+			if cfg.DryRun {
+				fmt.Fprintf(cfg.Out, "would write %s (%d modules)\n", name, len(info.Deps))
+				continue
+			}
+			data, err := sbom.Encode(format, b)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(cfg.Dir, name), data, 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cfg.Out, "Wrote %s (%d modules)\n", name, len(info.Deps))
+		}
+	}
+	return nil
+}
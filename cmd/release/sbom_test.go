@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aer/internal/release"
+)
+
+func TestRunSBOMDescribesEachArchive(t *testing.T) {
+	gitEnv(t)
+	platform := verifyPlatform(t)
+	assets := publish(t, newReleasedModule(t), platform)
+
+	var out bytes.Buffer
+	if err := runSBOM(sbomConfig{Dir: assets, Version: "v1.0.0", Out: &out}); err != nil {
+		t.Fatalf("runSBOM: %v\n%s", err, out.String())
+	}
+	data, err := os.ReadFile(filepath.Join(assets, release.SBOMName(platform, "v1.0.0", ".cdx.json")))
+	if err != nil {
+		t.Fatalf("read CycloneDX SBOM: %v\n%s", err, out.String())
+	}
+	var bom struct {
+		Metadata struct {
+			Component struct {
+				PURL string `json:"purl"`
+			} `json:"component"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &bom); err != nil {
+		t.Fatalf("decode CycloneDX SBOM: %v", err)
+	}
+	if bom.Metadata.Component.PURL != "pkg:github/octoberswimmer/aer-dist@v1.0.0" {
+		t.Fatalf("unexpected application purl %q", bom.Metadata.Component.PURL)
+	}
+	if _, err := os.Stat(filepath.Join(assets, release.SBOMName(platform, "v1.0.0", ".spdx.json"))); err != nil {
+		t.Fatalf("SPDX SBOM not written: %v", err)
+	}
+
+	// The manifest written afterwards lists both documents.
+	if err := runChecksums(checksumsConfig{Dir: assets, Version: "v1.0.0", Out: &out}); err != nil {
+		t.Fatalf("runChecksums: %v", err)
+	}
+	files, err := releaseAssets(assets, "v1.0.0")
+	if err != nil {
+		t.Fatalf("releaseAssets: %v", err)
+	}
+	if joined := strings.Join(files, "\n"); !strings.Contains(joined, ".cdx.json") || !strings.Contains(joined, ".spdx.json") {
+		t.Fatalf("SBOMs are not published:\n%s", joined)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"aer/internal/release"
+)
+
+const (
+	defaultModule   = "github.com/octoberswimmer/aer"
+	defaultUpstream = "https://github.com/octoberswimmer/aer.git"
+	defaultWorkflow = ".github/workflows/oss-tests.yml"
+)
+
+type tagConfig struct {
+	Dir        string
+	Module     string
+	Upstream   string
+	Workflow   string
+	Version    string
+	Prerelease bool
+	Sign       bool
+	DryRun     bool
+	Go         string
+	Out        io.Writer
+}
+
+func tagMain(args []string) error {
+	cfg := tagConfig{Out: os.Stdout}
+	fs := flag.NewFlagSet("release tag", flag.ContinueOnError)
+	fs.StringVar(&cfg.Dir, "dir", ".", "aer-dist checkout to update")
+	fs.StringVar(&cfg.Module, "module", defaultModule, "aer module path in go.mod")
+	fs.StringVar(&cfg.Upstream, "upstream", defaultUpstream, "git URL of the aer repository whose tags are released")
+	fs.StringVar(&cfg.Workflow, "workflow", defaultWorkflow, "workflow whose AER_VERSION is updated, relative to --dir")
+	fs.StringVar(&cfg.Version, "version", "", "upstream tag to release (defaults to the next one after the current version)")
+	fs.BoolVar(&cfg.Prerelease, "prerelease", false, "consider pre-release tags when picking the next version")
+	fs.BoolVar(&cfg.Sign, "sign", true, "create a signed tag (git tag -s); otherwise an annotated tag")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "print the planned changes without making them")
+	fs.StringVar(&cfg.Go, "go", "go", "go command used to update and build the module")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return runTag(cfg)
+}
+
+// runTag updates the aer dependency to the next upstream tag, points the
+// test workflow at it, checks that everything builds, then commits and tags
+// the result with the upstream tag's message.
+func runTag(cfg tagConfig) error {
+	git := &runner{Dir: cfg.Dir, DryRun: cfg.DryRun, Out: cfg.Out}
+
+	status, err := git.output("git", "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		return fmt.Errorf("working tree must be clean before tagging:\n%s", status)
+	}
+
+	gomod, err := os.ReadFile(filepath.Join(cfg.Dir, "go.mod"))
+	if err != nil {
+		return err
+	}
+	current, err := moduleVersion(gomod, cfg.Module)
+	if err != nil {
+		return err
+	}
+
+	tags, err := remoteTags(git, cfg.Upstream)
+	if err != nil {
+		return err
+	}
+	next := cfg.Version
+	if next == "" {
+		next, err = nextVersion(tags, current, cfg.Prerelease)
+		if err != nil {
+			return err
+		}
+	} else if err := checkVersion(tags, current, next); err != nil {
+		return err
+	}
+	if git.succeeds("git", "rev-parse", "--verify", "--quiet", "refs/tags/"+next) {
+		return fmt.Errorf("tag %s already exists locally", next)
+	}
+
+	message, err := tagMessage(cfg.Upstream, next)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.Out, "Updating %s from %s to %s\n", cfg.Module, current, next)
+
+	if err := git.run(cfg.Go, "get", cfg.Module+"@"+next); err != nil {
+		return err
+	}
+	if err := git.run(cfg.Go, "mod", "tidy"); err != nil {
+		return err
+	}
+	if err := updateWorkflow(cfg, next); err != nil {
+		return err
+	}
+	if err := git.run(cfg.Go, "build", "./..."); err != nil {
+		return err
+	}
+
+	paths := []string{"go.mod", cfg.Workflow}
+	if _, err := os.Stat(filepath.Join(cfg.Dir, "go.sum")); err == nil {
+		paths = append(paths, "go.sum")
+	}
+	if err := git.run("git", append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if !cfg.DryRun && git.succeeds("git", "diff", "--cached", "--quiet") {
+		return fmt.Errorf("updating to %s produced no changes", next)
+	}
+	if err := git.run("git", "commit", "-m", "Update aer to "+next); err != nil {
+		return err
+	}
+
+	tagFlag := "-a"
+	if cfg.Sign {
+		tagFlag = "-s"
+	}
+	if err := git.run("git", "tag", tagFlag, next, "-m", message); err != nil {
+		return err
+	}
+	if cfg.DryRun {
+		fmt.Fprintf(cfg.Out, "Dry run: would tag %s with message:\n%s\n", next, message)
+		return nil
+	}
+	fmt.Fprintf(cfg.Out, "Tagged %s. Build and publish it with `make release VERSION=%s`.\n", next, next)
+	return nil
+}
+
+// updateWorkflow sets AER_VERSION in the test workflow to version.
+func updateWorkflow(cfg tagConfig, version string) error {
+	path := filepath.Join(cfg.Dir, cfg.Workflow)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated, previous, err := setWorkflowValue(data, "AER_VERSION", version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cfg.Workflow, err)
+	}
+	if cfg.DryRun {
+		fmt.Fprintf(cfg.Out, "would set AER_VERSION in %s: %s -> %s\n", cfg.Workflow, strings.Join(previous, ", "), version)
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, updated, info.Mode().Perm())
+}
+
+// moduleVersion returns the version of module required by a go.mod file.
+func moduleVersion(gomod []byte, module string) (string, error) {
+	inRequire := false
+	scanner := bufio.NewScanner(bytes.NewReader(gomod))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case len(fields) == 2 && fields[0] == "require" && fields[1] == "(":
+			inRequire = true
+			continue
+		case inRequire && fields[0] == ")":
+			inRequire = false
+			continue
+		case !inRequire && fields[0] == "require":
+			fields = fields[1:]
+		case !inRequire:
+			continue
+		}
+		if len(fields) >= 2 && fields[0] == module {
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("go.mod does not require %s", module)
+}
+
+// remoteTags lists the tags of a remote repository.
+func remoteTags(git *runner, url string) ([]string, error) {
+	out, err := git.output("git", "ls-remote", "--tags", "--refs", url)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(fields[1], "refs/tags/"); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// nextVersion picks the lowest semantic-version tag above current. Tags that
+// are not semantic versions are ignored, as are pre-releases unless
+// prerelease is set.
+func nextVersion(tags []string, current string, prerelease bool) (string, error) {
+	cur, err := release.ParseVersion(current)
+	if err != nil {
+		return "", fmt.Errorf("current version: %w", err)
+	}
+	var next string
+	var best release.Version
+	for _, tag := range tags {
+		v, err := release.ParseVersion(tag)
+		if err != nil || (v.Pre != "" && !prerelease) || v.Compare(cur) <= 0 {
+			continue
+		}
+		if next == "" || v.Compare(best) < 0 {
+			next, best = tag, v
+		}
+	}
+	if next == "" {
+		return "", fmt.Errorf("already on the latest aer tag (%s)", current)
+	}
+	return next, nil
+}
+
+// checkVersion validates an explicitly requested version: it must be an
+// upstream tag newer than the current one.
+func checkVersion(tags []string, current, version string) error {
+	if _, err := release.ParseVersion(version); err != nil {
+		return err
+	}
+	found := false
+	for _, tag := range tags {
+		found = found || tag == version
+	}
+	if !found {
+		return fmt.Errorf("%s is not an upstream tag", version)
+	}
+	if release.CompareVersions(version, current) <= 0 {
+		return fmt.Errorf("%s is not newer than the current version %s", version, current)
+	}
+	return nil
+}
+
+// tagMessage fetches an upstream tag into a scratch repository and returns
+// its message, so the release tag carries the same notes.
+func tagMessage(url, tag string) (string, error) {
+	dir, err := os.MkdirTemp("", "aer-release-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	scratch := &runner{Dir: dir}
+	if _, err := scratch.output("git", "init", "-q"); err != nil {
+		return "", err
+	}
+	ref := "refs/tags/" + tag
+	if _, err := scratch.output("git", "fetch", "-q", "--depth=1", "--no-tags", url, ref+":"+ref); err != nil {
+		return "", err
+	}
+	message, err := scratch.output("git", "for-each-ref", "--format=%(contents)", ref)
+	if err != nil {
+		return "", err
+	}
+	if message == "" {
+		message = "Release " + tag
+	}
+	return message, nil
+}
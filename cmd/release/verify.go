@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"aer/internal/github"
+	"aer/internal/installer"
+	"aer/internal/release"
+	"aer/internal/selfupdate"
+)
+
+type verifyConfig struct {
+	Dir      string
+	Version  string
+	Platform string
+	Repo     string
+	AssetDir string
+	LDFlags  string
+	Go       string
+	GitHub   *github.Client
+	Out      io.Writer
+}
+
+func verifyMain(args []string) error {
+	cfg := verifyConfig{Out: os.Stdout}
+	fs := flag.NewFlagSet("release verify", flag.ContinueOnError)
+	fs.StringVar(&cfg.Dir, "dir", ".", "aer-dist checkout holding the release tag")
+	fs.StringVar(&cfg.Version, "version", "", "release tag to reproduce (required)")
+	fs.StringVar(&cfg.Platform, "platform", runtime.GOOS+"_"+runtime.GOARCH, "platform of the archive to reproduce, such as linux_amd64")
+	fs.StringVar(&cfg.Repo, "repo", selfupdate.DefaultRepo, "repository the release is published in")
+	fs.StringVar(&cfg.AssetDir, "assets", "", "read the published archive from this directory instead of GitHub")
+	fs.StringVar(&cfg.LDFlags, "ldflags", "", "linker flags of the published build (defaults to those set by the Makefile for the tag)")
+	fs.StringVar(&cfg.Go, "go", "go", "go command used to rebuild")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg.GitHub = github.NewClient()
+	return runVerify(cfg)
+}
+
+// runVerify rebuilds the binary published for one platform of a release
+// from the tagged sources, with the pinned go.mod and go.sum and an empty
+// module cache, and checks that it is byte-for-byte identical to the
+// published one. The build settings (Go version, flags and target) are taken
+// from the build information embedded in the published binary, so they are
+// reproduced rather than assumed.
+func runVerify(cfg verifyConfig) error {
+	if _, err := release.ParseVersion(cfg.Version); err != nil {
+		return fmt.Errorf("--version: %w", err)
+	}
+	goos, _, ok := strings.Cut(cfg.Platform, "_")
+	if !ok {
+		return fmt.Errorf("--platform %q is not of the form <os>_<arch>", cfg.Platform)
+	}
+	if goos == "darwin" {
+		return errors.New("darwin builds are code-signed and notarized after linking, so they cannot be reproduced byte-for-byte")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "aer-verify-")
+	if err != nil {
+		return err
+	}
+	defer removeAll(tmpDir)
+
+	archive := "aer_" + cfg.Platform + "_" + cfg.Version + ".zip"
+	archivePath := filepath.Join(tmpDir, archive)
+	binaryName, err := fetchPublished(cfg, archive, archivePath)
+	if err != nil {
+		return err
+	}
+	publishedPath, err := installer.ExtractBinary(archivePath, binaryName, filepath.Join(tmpDir, "published"))
+	if err != nil {
+		return fmt.Errorf("extract %s: %w", archive, err)
+	}
+	published, err := buildinfo.ReadFile(publishedPath)
+	if err != nil {
+		return fmt.Errorf("read build information from the published binary: %w", err)
+	}
+
+	git := &runner{Dir: cfg.Dir, Out: cfg.Out}
+	if !git.succeeds("git", "rev-parse", "--verify", "--quiet", "refs/tags/"+cfg.Version) {
+		return fmt.Errorf("tag %s does not exist in %s; fetch it first", cfg.Version, cfg.Dir)
+	}
+	srcDir := filepath.Join(tmpDir, "src")
+	if _, err := git.output("git", "clone", "-q", "--no-hardlinks", cfg.Dir, srcDir); err != nil {
+		return err
+	}
+	src := &runner{Dir: srcDir, Out: cfg.Out}
+	if _, err := src.output("git", "checkout", "-q", "--detach", "refs/tags/"+cfg.Version); err != nil {
+		return err
+	}
+
+	flags, env := rebuildSettings(published)
+	if !hasSetting(published, "-ldflags") {
+		// The go command does not record -ldflags for -trimpath builds, so
+		// rebuild with the flags the Makefile sets for a release.
+		ldflags := cfg.LDFlags
+		if ldflags == "" {
+			if ldflags, err = releaseLDFlags(src, cfg.Version); err != nil {
+				return err
+			}
+		}
+		flags = append(flags, "-ldflags="+ldflags)
+	}
+	goVersion, _, _ := strings.Cut(published.GoVersion, " ")
+	rebuiltPath := filepath.Join(tmpDir, "rebuilt", binaryName)
+	if err := os.MkdirAll(filepath.Dir(rebuiltPath), 0o755); err != nil {
+		return err
+	}
+	env = append(env,
+		"GOTOOLCHAIN="+goVersion,
+		"GOFLAGS=-mod=readonly",
+		"GOWORK=off",
+		"GOMODCACHE="+filepath.Join(tmpDir, "modcache"),
+	)
+	args := append([]string{"build"}, flags...)
+	args = append(args, "-o", rebuiltPath, published.Path)
+	fmt.Fprintf(cfg.Out, "Rebuilding %s for %s with %s: %s %s\n", cfg.Version, cfg.Platform, goVersion, strings.Join(env, " "), formatCommand(cfg.Go, args))
+
+	build := exec.Command(cfg.Go, args...)
+	build.Dir = srcDir
+	build.Env = append(os.Environ(), env...)
+	build.Stdout = cfg.Out
+	build.Stderr = cfg.Out
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("rebuild: %w", err)
+	}
+
+	want, err := release.FileSHA256(publishedPath)
+	if err != nil {
+		return err
+	}
+	got, err := release.FileSHA256(rebuiltPath)
+	if err != nil {
+		return err
+	}
+	if got == want {
+		fmt.Fprintf(cfg.Out, "Reproduced %s from %s: sha256 %s\n", binaryName, archive, got)
+		return nil
+	}
+
+	fmt.Fprintf(cfg.Out, "Published %s: sha256 %s\nRebuilt   %s: sha256 %s\n", binaryName, want, binaryName, got)
+	rebuilt, err := buildinfo.ReadFile(rebuiltPath)
+	if err != nil {
+		return fmt.Errorf("read build information from the rebuilt binary: %w", err)
+	}
+	differences := diffBuildInfo(published, rebuilt)
+	if len(differences) == 0 {
+		differences = []string{"build information is identical; the difference is in the compiled code"}
+	}
+	for _, d := range differences {
+		fmt.Fprintf(cfg.Out, "  %s\n", d)
+	}
+	return fmt.Errorf("could not reproduce %s from %s", binaryName, archive)
+}
+
+// fetchPublished copies the published archive to dest and verifies it
+// against the release manifest, or SHA256SUMS when there is no manifest. It
+// returns the name of the binary in the archive.
+func fetchPublished(cfg verifyConfig, archive, dest string) (string, error) {
+	read := func(name string, w io.Writer) error {
+		if cfg.AssetDir == "" {
+			return cfg.GitHub.DownloadAsset(cfg.Repo, cfg.Version, name, w)
+		}
+		f, err := os.Open(filepath.Join(cfg.AssetDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return github.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	}
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	err = read(archive, out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("fetch %s: %w", archive, err)
+	}
+
+	binary := "aer"
+	if strings.HasPrefix(archive, "aer_windows_") {
+		binary = "aer.exe"
+	}
+	var sums release.Checksums
+	var buf bytes.Buffer
+	switch err := read(release.ManifestName(cfg.Version), &buf); {
+	case err == nil:
+		manifest, err := release.ParseManifest(buf.Bytes())
+		if err != nil {
+			return "", err
+		}
+		for _, asset := range manifest.Assets {
+			if asset.Name == archive && asset.Binary != "" {
+				binary = asset.Binary
+			}
+		}
+		sums = manifest.Checksums()
+	case errors.Is(err, github.ErrNotFound):
+		buf.Reset()
+		if err := read(release.ChecksumsName(cfg.Version), &buf); err != nil && !errors.Is(err, github.ErrNotFound) {
+			return "", err
+		} else if err == nil {
+			if sums, err = release.ParseChecksums(buf.Bytes()); err != nil {
+				return "", err
+			}
+		}
+	default:
+		return "", err
+	}
+	if sums == nil {
+		fmt.Fprintf(cfg.Out, "warning: %s publishes no checksums; comparing against the archive as found\n", cfg.Version)
+	} else if err := sums.Verify(dest, archive); err != nil {
+		return "", err
+	}
+	return binary, nil
+}
+
+// rebuildSettings returns the go build flags and environment that reproduce
+// the build settings recorded in a binary. A binary without a VCS stamp was
+// built with -buildvcs=false, which the go command does not record.
+func rebuildSettings(info *buildinfo.BuildInfo) (flags, env []string) {
+	if !hasSetting(info, "vcs") {
+		flags = append(flags, "-buildvcs=false")
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "-trimpath", "-race", "-msan", "-asan":
+			if s.Value == "true" {
+				flags = append(flags, s.Key)
+			}
+		case "-buildmode", "-compiler":
+			// Naming the default explicitly changes the build ID, so only
+			// pass these when the published build changed them.
+			if s.Value != "exe" && s.Value != "gc" {
+				flags = append(flags, s.Key+"="+s.Value)
+			}
+		case "-gcflags", "-asmflags", "-ldflags", "-tags":
+			flags = append(flags, s.Key+"="+s.Value)
+		case "CGO_ENABLED", "GOOS", "GOARCH", "GOEXPERIMENT",
+			"GO386", "GOAMD64", "GOARM", "GOARM64", "GOMIPS", "GOMIPS64", "GOPPC64", "GORISCV64", "GOWASM":
+			env = append(env, s.Key+"="+s.Value)
+		}
+	}
+	return flags, env
+}
+
+func hasSetting(info *buildinfo.BuildInfo, key string) bool {
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseLDFlags returns the linker flags the Makefile's GO_LDFLAGS expand
+// to when building the release tagged version. Keep the two in sync.
+func releaseLDFlags(git *runner, version string) (string, error) {
+	commit, err := git.output("git", "rev-parse", version+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	date, err := git.output("git", "log", "-1", "--format=%cI", commit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("-X main.version=%s -X aer/internal/buildinfo.commit=%s -X aer/internal/buildinfo.date=%s", version, commit, date), nil
+}
+
+// diffBuildInfo lists the differences between the build information of two
+// binaries: toolchain, main module, dependencies and build settings.
+func diffBuildInfo(published, rebuilt *buildinfo.BuildInfo) []string {
+	var diffs []string
+	differ := func(what, a, b string) {
+		if a != b {
+			diffs = append(diffs, fmt.Sprintf("%s: published %q, rebuilt %q", what, a, b))
+		}
+	}
+	differ("Go version", published.GoVersion, rebuilt.GoVersion)
+	differ("main module", moduleString(&published.Main), moduleString(&rebuilt.Main))
+
+	deps := func(info *buildinfo.BuildInfo) map[string]string {
+		m := make(map[string]string)
+		for _, dep := range info.Deps {
+			m[dep.Path] = moduleString(dep)
+		}
+		return m
+	}
+	publishedDeps, rebuiltDeps := deps(published), deps(rebuilt)
+	for _, dep := range published.Deps {
+		differ("dependency "+dep.Path, publishedDeps[dep.Path], rebuiltDeps[dep.Path])
+	}
+	for _, dep := range rebuilt.Deps {
+		if _, ok := publishedDeps[dep.Path]; !ok {
+			differ("dependency "+dep.Path, "", rebuiltDeps[dep.Path])
+		}
+	}
+
+	settings := func(info *buildinfo.BuildInfo) map[string]string {
+		m := make(map[string]string)
+		for _, s := range info.Settings {
+			m[s.Key] = s.Value
+		}
+		return m
+	}
+	publishedSettings, rebuiltSettings := settings(published), settings(rebuilt)
+	for _, s := range published.Settings {
+		differ("setting "+s.Key, s.Value, rebuiltSettings[s.Key])
+	}
+	for _, s := range rebuilt.Settings {
+		if _, ok := publishedSettings[s.Key]; !ok {
+			differ("setting "+s.Key, "", s.Value)
+		}
+	}
+	return diffs
+}
+
+func moduleString(m *debug.Module) string {
+	if m == nil {
+		return ""
+	}
+	s := m.Path + "@" + m.Version
+	if m.Sum != "" {
+		s += " " + m.Sum
+	}
+	if m.Replace != nil {
+		s += " => " + moduleString(m.Replace)
+	}
+	return s
+}
+
+// removeAll deletes dir, including a module cache whose files and
+// directories the go command makes read-only.
+func removeAll(dir string) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(path, 0o755)
+		}
+		return nil
+	})
+	os.RemoveAll(dir)
+}
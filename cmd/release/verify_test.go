@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"aer/internal/fakegithub"
+	"aer/internal/release"
+)
+
+// newReleasedModule creates a repository holding a small main module tagged
+// v1.0.0, standing in for aer-dist.
+func newReleasedModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	writeFile(t, filepath.Join(dir, "go.mod"), "module aer\n\ngo 1.22\n")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nvar version = \"dev\"\n\nfunc main() { println(version) }\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "initial")
+	git(t, dir, "tag", "-a", "v1.0.0", "-m", "aer v1.0.0")
+	return dir
+}
+
+// publish builds the module in dir with the Makefile's GO_BUILD_FLAGS and
+// GO_LDFLAGS and stages the archive and its manifest in a new asset
+// directory.
+func publish(t *testing.T, dir, platform string) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "aer")
+	ldflags := "-X main.version=v1.0.0 -X aer/internal/buildinfo.commit=" + git(t, dir, "rev-parse", "HEAD") +
+		" -X aer/internal/buildinfo.date=" + git(t, dir, "log", "-1", "--format=%cI")
+	cmd := exec.Command("go", "build", "-trimpath", "-buildvcs=false", "-ldflags="+ldflags, "-o", binary, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(binary)
+	if err != nil {
+		t.Fatalf("read binary: %v", err)
+	}
+
+	assets := t.TempDir()
+	archives := map[string][]byte{
+		"aer_" + platform + "_v1.0.0.zip": fakegithub.Zip(t, fakegithub.ZipFile{Name: "aer", Body: string(data), Mode: 0o755}),
+	}
+	for name, data := range archives {
+		writeFile(t, filepath.Join(assets, name), string(data))
+	}
+	writeFile(t, filepath.Join(assets, release.ManifestName("v1.0.0")), string(fakegithub.Manifest(t, "v1.0.0", archives)))
+	return assets
+}
+
+func verifyPlatform(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skipf("release binaries for %s are not reproduced by this test", runtime.GOOS)
+	}
+	return runtime.GOOS + "_" + runtime.GOARCH
+}
+
+func TestRunVerifyReproducesRelease(t *testing.T) {
+	gitEnv(t)
+	platform := verifyPlatform(t)
+	dir := newReleasedModule(t)
+	assets := publish(t, dir, platform)
+
+	var out bytes.Buffer
+	err := runVerify(verifyConfig{Dir: dir, Version: "v1.0.0", Platform: platform, AssetDir: assets, Go: "go", Out: &out})
+	if err != nil {
+		t.Fatalf("runVerify: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Reproduced aer from aer_"+platform+"_v1.0.0.zip") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "-buildvcs=false -trimpath '-ldflags=-X main.version=v1.0.0 -X aer/internal/buildinfo.commit=") {
+		t.Fatalf("rebuild did not reuse the published build flags:\n%s", out.String())
+	}
+}
+
+func TestRunVerifyIgnoresUntrackedFiles(t *testing.T) {
+	gitEnv(t)
+	platform := verifyPlatform(t)
+	dir := newReleasedModule(t)
+	// make dist leaves the archives and checksums untracked in the checkout
+	// it builds from.
+	writeFile(t, filepath.Join(dir, "SHA256SUMS-v1.0.0"), "untracked\n")
+	assets := publish(t, dir, platform)
+
+	var out bytes.Buffer
+	err := runVerify(verifyConfig{Dir: dir, Version: "v1.0.0", Platform: platform, AssetDir: assets, Go: "go", Out: &out})
+	if err != nil {
+		t.Fatalf("runVerify: %v\n%s", err, out.String())
+	}
+}
+
+func TestRunVerifyReportsDifferences(t *testing.T) {
+	gitEnv(t)
+	platform := verifyPlatform(t)
+	dir := newReleasedModule(t)
+	// Publish from a tree with uncommitted changes, which the tag cannot
+	// reproduce.
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nvar version = \"dev\"\n\nfunc main() { println(\"patched\", version) }\n")
+	assets := publish(t, dir, platform)
+
+	var out bytes.Buffer
+	err := runVerify(verifyConfig{Dir: dir, Version: "v1.0.0", Platform: platform, AssetDir: assets, Go: "go", Out: &out})
+	if err == nil || !strings.Contains(err.Error(), "could not reproduce aer") {
+		t.Fatalf("expected a reproduction failure, got %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "the difference is in the compiled code") {
+		t.Fatalf("differences do not explain the mismatch:\n%s", out.String())
+	}
+}
+
+func TestRunVerifyRejectsDarwin(t *testing.T) {
+	err := runVerify(verifyConfig{Version: "v1.0.0", Platform: "darwin_arm64", Out: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "notarized") {
+		t.Fatalf("expected darwin to be rejected, got %v", err)
+	}
+}
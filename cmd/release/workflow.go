@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// entryPattern matches a block mapping entry, optionally as a sequence item:
+// the leading "- " markers, the key (plain or quoted) and the raw value.
+var entryPattern = regexp.MustCompile(`^((?:-\s+)*)([^\s"'#:][^:#]*?|"[^"]*"|'[^']*')\s*:(?:\s+(.*))?$`)
+
+// blockScalarPattern matches the header of a literal or folded block scalar
+// such as "|", ">-" or "|2".
+var blockScalarPattern = regexp.MustCompile(`^[|>][0-9+-]*$`)
+
+// setWorkflowValue sets every scalar mapping entry named key in a workflow
+// file to value and returns the values it replaced. Indentation, quoting and
+// trailing comments are preserved. Comments and block scalars (such as
+// multi-line `run: |` scripts) are skipped, so a script that mentions the key
+// is never rewritten.
+func setWorkflowValue(data []byte, key, value string) ([]byte, []string, error) {
+	lines := strings.SplitAfter(string(data), "\n")
+	var previous []string
+	blockIndent := -1
+	for i, line := range lines {
+		body := strings.TrimRight(line, "\r\n")
+		ending := line[len(body):]
+		trimmed := strings.TrimLeft(body, " ")
+		indent := len(body) - len(trimmed)
+
+		if blockIndent >= 0 {
+			if strings.TrimSpace(trimmed) == "" || indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		match := entryPattern.FindStringSubmatch(trimmed)
+		if match == nil {
+			continue
+		}
+		dashes, name, raw := match[1], unquoteKey(match[2]), match[3]
+		scalar, rest := splitScalar(raw)
+		if blockScalarPattern.MatchString(scalar) {
+			blockIndent = indent + len(dashes)
+			continue
+		}
+		if name != key || scalar == "" {
+			continue
+		}
+
+		quote := ""
+		if c := scalar[0]; c == '"' || c == '\'' {
+			quote = string(c)
+			previous = append(previous, scalar[1:len(scalar)-1])
+		} else {
+			previous = append(previous, scalar)
+		}
+		prefix := body[:len(body)-len(raw)]
+		lines[i] = prefix + quote + value + quote + rest + ending
+	}
+	if len(previous) == 0 {
+		return nil, nil, fmt.Errorf("no %s entry found", key)
+	}
+	return []byte(strings.Join(lines, "")), previous, nil
+}
+
+// splitScalar separates a raw mapping value into the scalar itself
+// (including any quotes) and what follows it, such as a trailing comment.
+func splitScalar(raw string) (scalar, rest string) {
+	if raw == "" {
+		return "", ""
+	}
+	if c := raw[0]; c == '"' || c == '\'' {
+		for i := 1; i < len(raw); i++ {
+			switch {
+			case c == '"' && raw[i] == '\\':
+				i++
+			case c == '\'' && raw[i] == '\'' && i+1 < len(raw) && raw[i+1] == '\'':
+				i++
+			case raw[i] == c:
+				return raw[:i+1], raw[i+1:]
+			}
+		}
+		return raw, ""
+	}
+	end := len(raw)
+	if i := strings.Index(raw, " #"); i >= 0 {
+		end = i
+	}
+	scalar = strings.TrimRight(raw[:end], " \t")
+	return scalar, raw[len(scalar):]
+}
+
+func unquoteKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
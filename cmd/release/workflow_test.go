@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetWorkflowValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		previous []string
+	}{
+		{
+			name:     "top-level env",
+			input:    "env:\n  AER_VERSION: v0.0.101\n\njobs: {}\n",
+			want:     "env:\n  AER_VERSION: v0.0.102\n\njobs: {}\n",
+			previous: []string{"v0.0.101"},
+		},
+		{
+			name:     "quotes and comments are kept",
+			input:    "env:\n  AER_VERSION: \"v0.0.101\" # bumped by release\n  OTHER: x\n",
+			want:     "env:\n  AER_VERSION: \"v0.0.102\" # bumped by release\n  OTHER: x\n",
+			previous: []string{"v0.0.101"},
+		},
+		{
+			name:     "single-quoted with CRLF endings",
+			input:    "env:\r\n  'AER_VERSION': 'v0.0.101'\r\n",
+			want:     "env:\r\n  'AER_VERSION': 'v0.0.102'\r\n",
+			previous: []string{"v0.0.101"},
+		},
+		{
+			name: "block scalars and comments are skipped",
+			input: "# AER_VERSION: v0.0.1\n" +
+				"env:\n  AER_VERSION: v0.0.101\n" +
+				"jobs:\n  test:\n    steps:\n      - run: |\n          AER_VERSION: v0.0.1\n\n          echo done\n" +
+				"        env:\n          AER_VERSION: v0.0.100\n",
+			want: "# AER_VERSION: v0.0.1\n" +
+				"env:\n  AER_VERSION: v0.0.102\n" +
+				"jobs:\n  test:\n    steps:\n      - run: |\n          AER_VERSION: v0.0.1\n\n          echo done\n" +
+				"        env:\n          AER_VERSION: v0.0.102\n",
+			previous: []string{"v0.0.101", "v0.0.100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, previous, err := setWorkflowValue([]byte(tt.input), "AER_VERSION", "v0.0.102")
+			if err != nil {
+				t.Fatalf("setWorkflowValue: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("unexpected workflow:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(previous, tt.previous) {
+				t.Fatalf("previous = %v, want %v", previous, tt.previous)
+			}
+		})
+	}
+}
+
+func TestSetWorkflowValueRequiresKey(t *testing.T) {
+	input := "env:\n  OTHER: v1\njobs:\n  test:\n    steps:\n      - run: >-\n          AER_VERSION: v1\n"
+	if _, _, err := setWorkflowValue([]byte(input), "AER_VERSION", "v2"); err == nil {
+		t.Fatal("expected an error when the key only appears inside a script")
+	}
+}
@@ -2,7 +2,11 @@ module aer
 
 go 1.25.3
 
-require github.com/octoberswimmer/aer v0.0.101
+require (
+	github.com/octoberswimmer/aer v0.0.101
+	github.com/spf13/cobra v1.10.1
+	golang.org/x/crypto v0.46.0
+)
 
 require (
 	github.com/ForceCLI/force v1.4.3 // indirect
@@ -38,7 +42,6 @@ require (
 	github.com/shopspring/decimal v1.4.0 // indirect
 	github.com/sirupsen/logrus v1.9.3 // indirect
 	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e // indirect
-	github.com/spf13/cobra v1.10.1 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
 	github.com/tidwall/jsonc v0.3.2 // indirect
 	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b // indirect
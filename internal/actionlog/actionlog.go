@@ -0,0 +1,154 @@
+// Package actionlog writes leveled log lines and GitHub Actions workflow
+// commands (log groups, error and warning annotations, secret masks) for the
+// action helpers.
+package actionlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Logger writes log lines and workflow commands to a single stream. Workflow
+// commands are only recognized by the runner on stdout or stderr, so all
+// output goes through one writer to keep it ordered.
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	debug bool
+	exit  func(int)
+}
+
+// New returns a Logger writing to out, with debug output enabled when debug
+// is true.
+func New(out io.Writer, debug bool) *Logger {
+	return &Logger{out: out, debug: debug, exit: os.Exit}
+}
+
+var std = New(os.Stdout, RunnerDebug())
+
+// Default returns the Logger used by the package-level functions.
+func Default() *Logger { return std }
+
+// RunnerDebug reports whether the workflow was re-run with debug logging
+// enabled, which the runner signals with RUNNER_DEBUG=1.
+func RunnerDebug() bool {
+	return os.Getenv("RUNNER_DEBUG") == "1"
+}
+
+// SetDebug enables or disables debug output.
+func (l *Logger) SetDebug(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debug = enabled
+}
+
+// DebugEnabled reports whether debug output is enabled.
+func (l *Logger) DebugEnabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.debug
+}
+
+// Debugf logs a message only in debug mode.
+func (l *Logger) Debugf(format string, args ...any) {
+	if !l.DebugEnabled() {
+		return
+	}
+	l.writeLines("debug: ", fmt.Sprintf(format, args...))
+}
+
+// Infof logs a plain message.
+func (l *Logger) Infof(format string, args ...any) {
+	l.writeLines("", fmt.Sprintf(format, args...))
+}
+
+// Warningf logs a message as a warning annotation.
+func (l *Logger) Warningf(format string, args ...any) {
+	l.command("warning", fmt.Sprintf(format, args...))
+}
+
+// Errorf logs a message as an error annotation. Multi-line messages are kept
+// in a single annotation so the cause is not lost.
+func (l *Logger) Errorf(format string, args ...any) {
+	l.command("error", fmt.Sprintf(format, args...))
+}
+
+// Fatalf logs an error annotation and exits with status 1.
+func (l *Logger) Fatalf(format string, args ...any) {
+	l.Errorf(format, args...)
+	l.exit(1)
+}
+
+// Fatal logs its arguments, formatted like fmt.Sprint, as an error
+// annotation and exits with status 1.
+func (l *Logger) Fatal(args ...any) {
+	l.Fatalf("%s", fmt.Sprint(args...))
+}
+
+// Group starts a collapsible log group and returns a function that ends it.
+func (l *Logger) Group(title string) func() {
+	l.command("group", title)
+	return func() { l.command("endgroup", "") }
+}
+
+// Mask asks the runner to redact value from all later log output. Empty
+// values are ignored, since masking them would redact nothing useful.
+func (l *Logger) Mask(value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	l.command("add-mask", value)
+}
+
+func (l *Logger) command(name, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.out, "::%s::%s\n", name, escapeData(message))
+}
+
+func (l *Logger) writeLines(prefix, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(message, "\n"), "\n") {
+		fmt.Fprintf(l.out, "%s%s\n", prefix, line)
+	}
+}
+
+// escapeData encodes the characters that would otherwise end or corrupt a
+// workflow command's message.
+func escapeData(s string) string {
+	s = strings.TrimRight(s, "\n")
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// SetDebug enables or disables debug output on the default Logger.
+func SetDebug(enabled bool) { std.SetDebug(enabled) }
+
+// Debugf logs a message on the default Logger only in debug mode.
+func Debugf(format string, args ...any) { std.Debugf(format, args...) }
+
+// Infof logs a plain message on the default Logger.
+func Infof(format string, args ...any) { std.Infof(format, args...) }
+
+// Warningf logs a warning annotation on the default Logger.
+func Warningf(format string, args ...any) { std.Warningf(format, args...) }
+
+// Errorf logs an error annotation on the default Logger.
+func Errorf(format string, args ...any) { std.Errorf(format, args...) }
+
+// Fatalf logs an error annotation on the default Logger and exits.
+func Fatalf(format string, args ...any) { std.Fatalf(format, args...) }
+
+// Fatal logs an error annotation on the default Logger and exits.
+func Fatal(args ...any) { std.Fatal(args...) }
+
+// Group starts a log group on the default Logger.
+func Group(title string) func() { return std.Group(title) }
+
+// Mask redacts value from later output on the default Logger.
+func Mask(value string) { std.Mask(value) }
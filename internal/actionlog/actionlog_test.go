@@ -0,0 +1,51 @@
+package actionlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWorkflowCommands(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, false)
+
+	end := l.Group("Install aer")
+	l.Infof("Downloading %s", "aer_linux_amd64_v1.zip")
+	l.Debugf("hidden")
+	l.Warningf("no checksums")
+	l.Errorf("verify failed: 100%%\n  host: linux/amd64\n")
+	l.Mask("s3cr3t")
+	l.Mask("")
+	end()
+
+	want := "::group::Install aer\n" +
+		"Downloading aer_linux_amd64_v1.zip\n" +
+		"::warning::no checksums\n" +
+		"::error::verify failed: 100%25%0A  host: linux/amd64\n" +
+		"::add-mask::s3cr3t\n" +
+		"::endgroup::\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestDebugf(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, true)
+	l.Debugf("args: %s\nenv: %s", "-f Foo", "CI=true")
+	if want := "debug: args: -f Foo\ndebug: env: CI=true\n"; buf.String() != want {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestFatalfExits(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, false)
+	code := -1
+	l.exit = func(c int) { code = c }
+
+	l.Fatalf("read lockfile: %v", "boom")
+	if code != 1 || buf.String() != "::error::read lockfile: boom\n" {
+		t.Fatalf("unexpected exit %d and output %q", code, buf.String())
+	}
+}
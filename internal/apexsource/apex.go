@@ -0,0 +1,33 @@
+package apexsource
+
+import "regexp"
+
+var (
+	// tokenPattern matches comments and string literals in one pass, so
+	// whichever starts first wins: "//" inside a string is not a comment,
+	// and a quote inside a comment does not start a string.
+	tokenPattern     = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*|'(?:\\.|[^'\\])*'`)
+	classDeclPattern = regexp.MustCompile(`(?i)\bclass\s+\w+`)
+	isTestAnnotation = regexp.MustCompile(`(?i)@istest\b`)
+)
+
+// Strip removes comments from Apex source and empties its string literals,
+// so that scanning it only sees code.
+func Strip(content string) string {
+	return tokenPattern.ReplaceAllStringFunc(content, func(token string) string {
+		if token[0] == '\'' {
+			return "''"
+		}
+		return ""
+	})
+}
+
+// IsTestClass reports whether the top-level class in Apex source, as returned
+// by Strip, is annotated with @IsTest.
+func IsTestClass(code string) bool {
+	loc := classDeclPattern.FindStringIndex(code)
+	if loc == nil {
+		return false
+	}
+	return isTestAnnotation.MatchString(code[:loc[0]])
+}
@@ -0,0 +1,20 @@
+// Package apexsource handles the Salesforce project source the action helpers
+// scan: the source input's paths and the Apex classes under them.
+package apexsource
+
+import "strings"
+
+// SplitPaths parses the action's source input: one path per line, or
+// space-separated paths when given on a single line.
+func SplitPaths(value string) []string {
+	var paths []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	if len(paths) == 1 && strings.Contains(paths[0], " ") {
+		paths = strings.Fields(paths[0])
+	}
+	return paths
+}
@@ -0,0 +1,68 @@
+package apexsource
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPaths(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{"sfdx", []string{"sfdx"}},
+		{"force-app  other-app", []string{"force-app", "other-app"}},
+		{"force app\nother app\n", []string{"force app", "other app"}},
+		{"\n force-app \n\n other app\n", []string{"force-app", "other app"}},
+		{"  \n\t\n", nil},
+	}
+	for _, tc := range cases {
+		if got := SplitPaths(tc.input); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("SplitPaths(%q): expected %q, got %q", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestIsTestClass(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"annotated", "@IsTest\nprivate class AccountTest {}", true},
+		{"lowercase with args", "@isTest(SeeAllData=false)\npublic with sharing class Foo {}", true},
+		{"plain class", "public class Account {\n @IsTest static void inner() {}\n}", false},
+		{"commented annotation", "// @IsTest\npublic class Account {}", false},
+		{"block comment", "/* @isTest */ public class Account {}", false},
+		{"string literal", "public class Account { String s = '@IsTest class X'; }", false},
+	}
+	for _, tc := range cases {
+		if got := IsTestClass(Strip(tc.content)); got != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestStrip(t *testing.T) {
+	got := Strip("/* Uses\n AccountService */ System.debug('it\\'s InvoiceHandler'); // Report\nx = 1;")
+	if want := " System.debug(''); \nx = 1;"; got != want {
+		t.Fatalf("Strip: expected %q, got %q", want, got)
+	}
+}
+
+func TestStripCommentMarkersInStrings(t *testing.T) {
+	cases := []struct {
+		content string
+		want    string
+	}{
+		{"String url = 'http://x'; InvoiceHandler.run();", "String url = ''; InvoiceHandler.run();"},
+		{"String s = '/* x */'; InvoiceHandler.run(); // done", "String s = ''; InvoiceHandler.run(); "},
+		{"// it's InvoiceHandler\nx = 1;", "\nx = 1;"},
+		{"/* don't */ InvoiceHandler.run('*/');", " InvoiceHandler.run('');"},
+	}
+	for _, tc := range cases {
+		if got := Strip(tc.content); got != tc.want {
+			t.Fatalf("Strip(%q): expected %q, got %q", tc.content, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Package artifact uploads workflow artifacts to the GitHub Actions results
+// service, the backend behind actions/upload-artifact@v4. An upload creates
+// the artifact, stores its zip in the blob storage URL the service signs, and
+// finalizes it with the zip's size and digest.
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aer/internal/actionlog"
+)
+
+// twirpService is the path of the artifact service's Twirp endpoints.
+const twirpService = "/twirp/github.actions.results.api.v1.ArtifactService/"
+
+// Client uploads artifacts for the job that owns Token.
+type Client struct {
+	HTTP       *http.Client
+	ResultsURL string // results service root, from ACTIONS_RESULTS_URL
+	Token      string // job's runtime token, from ACTIONS_RUNTIME_TOKEN
+}
+
+// NewClient returns a Client configured from ACTIONS_RESULTS_URL and
+// ACTIONS_RUNTIME_TOKEN.
+func NewClient() *Client {
+	return &Client{
+		HTTP:       http.DefaultClient,
+		ResultsURL: os.Getenv("ACTIONS_RESULTS_URL"),
+		Token:      os.Getenv("ACTIONS_RUNTIME_TOKEN"),
+	}
+}
+
+// File is a file to include in an artifact.
+type File struct {
+	Name string // slash-separated path inside the artifact
+	Path string // file on disk
+}
+
+// Artifact is an uploaded artifact.
+type Artifact struct {
+	ID   int64
+	Name string
+	Size int64
+}
+
+// Upload zips files into the artifact name and uploads it. A retention of
+// zero keeps the repository's default.
+func (c *Client) Upload(name string, files []File, retentionDays int) (Artifact, error) {
+	if err := ValidateName(name); err != nil {
+		return Artifact{}, err
+	}
+	if c.ResultsURL == "" || c.Token == "" {
+		return Artifact{}, errors.New("ACTIONS_RESULTS_URL and ACTIONS_RUNTIME_TOKEN must be set to upload artifacts")
+	}
+	ids, err := parseBackendIDs(c.Token)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	archive, err := os.CreateTemp("", "aer-artifact-*.zip")
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	digest := sha256.New()
+	size, err := writeZip(io.MultiWriter(archive, digest), files)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("zip artifact: %w", err)
+	}
+
+	create := createRequest{backendIDs: ids, Name: name, Version: 4}
+	if retentionDays > 0 {
+		create.ExpiresAt = time.Now().UTC().AddDate(0, 0, retentionDays).Format(time.RFC3339)
+	}
+	var created createResponse
+	if err := c.call("CreateArtifact", create, &created); err != nil {
+		return Artifact{}, err
+	}
+	if !created.OK || created.SignedUploadURL == "" {
+		return Artifact{}, fmt.Errorf("CreateArtifact: the results service did not accept artifact %q", name)
+	}
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return Artifact{}, err
+	}
+	if err := c.putBlob(created.SignedUploadURL, archive, size); err != nil {
+		return Artifact{}, err
+	}
+
+	finalize := finalizeRequest{backendIDs: ids, Name: name, Size: size, Hash: "sha256:" + hex.EncodeToString(digest.Sum(nil))}
+	var finalized finalizeResponse
+	if err := c.call("FinalizeArtifact", finalize, &finalized); err != nil {
+		return Artifact{}, err
+	}
+	if !finalized.OK {
+		return Artifact{}, fmt.Errorf("FinalizeArtifact: the results service did not accept artifact %q", name)
+	}
+	id, err := finalized.ArtifactID.Int64()
+	if err != nil {
+		return Artifact{}, fmt.Errorf("FinalizeArtifact: artifact ID %q: %w", finalized.ArtifactID, err)
+	}
+	return Artifact{ID: id, Name: name, Size: size}, nil
+}
+
+// ValidateName rejects artifact names the results service does not accept.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("artifact name is empty")
+	}
+	if i := strings.IndexAny(name, "\":<>|*?\r\n\\/"); i >= 0 {
+		return fmt.Errorf("artifact name %q contains %q, which is not allowed", name, name[i:i+1])
+	}
+	return nil
+}
+
+// backendIDs identify the workflow run and job an artifact belongs to.
+type backendIDs struct {
+	WorkflowRunBackendID    string `json:"workflow_run_backend_id"`
+	WorkflowJobRunBackendID string `json:"workflow_job_run_backend_id"`
+}
+
+type createRequest struct {
+	backendIDs
+	Name      string `json:"name"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	Version   int    `json:"version"`
+}
+
+type createResponse struct {
+	OK              bool   `json:"ok"`
+	SignedUploadURL string `json:"signed_upload_url"`
+}
+
+type finalizeRequest struct {
+	backendIDs
+	Name string `json:"name"`
+	Size int64  `json:"size,string"`
+	Hash string `json:"hash"`
+}
+
+type finalizeResponse struct {
+	OK         bool        `json:"ok"`
+	ArtifactID json.Number `json:"artifact_id"`
+}
+
+// parseBackendIDs reads the run and job IDs from the runtime token's scopes. The
+// token is a JWT whose scp claim includes
+// "Actions.Results:<run backend ID>:<job backend ID>". The signature is the
+// service's to check.
+func parseBackendIDs(token string) (backendIDs, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return backendIDs{}, errors.New("ACTIONS_RUNTIME_TOKEN is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return backendIDs{}, fmt.Errorf("decode ACTIONS_RUNTIME_TOKEN: %w", err)
+	}
+	var claims struct {
+		Scope string `json:"scp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return backendIDs{}, fmt.Errorf("decode ACTIONS_RUNTIME_TOKEN: %w", err)
+	}
+	for _, scope := range strings.Fields(claims.Scope) {
+		ids, ok := strings.CutPrefix(scope, "Actions.Results:")
+		if !ok {
+			continue
+		}
+		run, job, ok := strings.Cut(ids, ":")
+		if !ok || run == "" || job == "" {
+			break
+		}
+		return backendIDs{WorkflowRunBackendID: run, WorkflowJobRunBackendID: job}, nil
+	}
+	return backendIDs{}, errors.New("ACTIONS_RUNTIME_TOKEN has no Actions.Results scope")
+}
+
+// writeZip writes files to w as a zip archive and returns its size.
+func writeZip(w io.Writer, files []File) (int64, error) {
+	counter := &countingWriter{w: w}
+	zw := zip.NewWriter(counter)
+	for _, f := range files {
+		if err := addFile(zw, f); err != nil {
+			return 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+func addFile(zw *zip.Writer, f File) error {
+	in, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(f.Name)
+	header.Method = zip.Deflate
+	out, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// call invokes a Twirp method of the artifact service with JSON bodies.
+func (c *Client) call(method string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimRight(c.ResultsURL, "/") + twirpService + method
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+
+	actionlog.Debugf("POST %s", endpoint)
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	actionlog.Debugf("POST %s: %s", endpoint, resp.Status)
+	if resp.StatusCode != http.StatusOK {
+		var twirpErr struct {
+			Code string `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&twirpErr) == nil && twirpErr.Msg != "" {
+			return fmt.Errorf("%s: %s: %s (%s)", method, resp.Status, twirpErr.Msg, twirpErr.Code)
+		}
+		return fmt.Errorf("%s: unexpected HTTP status %s", method, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s: decode response: %w", method, err)
+	}
+	return nil
+}
+
+// putBlob stores the artifact zip at the signed blob storage URL.
+func (c *Client) putBlob(uploadURL string, body io.Reader, size int64) error {
+	req, err := http.NewRequest(http.MethodPut, uploadURL, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/zip")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+
+	// The signed URL carries a SAS token, so it is not logged.
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("upload artifact zip: %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upload artifact zip: unexpected HTTP status %s", resp.Status)
+	}
+	return nil
+}
+
+// redactURL drops the URL from an HTTP client error.
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,109 @@
+package artifact_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aer/internal/artifact"
+	"aer/internal/fakeartifacts"
+)
+
+func writeFile(t *testing.T, path, body string) string {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	server := fakeartifacts.New(t)
+	files := []artifact.File{
+		{Name: "aer-test-results.xml", Path: writeFile(t, filepath.Join(dir, "results.xml"), "<testsuite/>")},
+		{Name: "html/index.html", Path: writeFile(t, filepath.Join(dir, "index.html"), "<html></html>")},
+	}
+
+	uploaded, err := server.Client().Upload("aer-reports", files, 3)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	artifacts := server.Artifacts()
+	if len(artifacts) != 1 || uploaded.ID != artifacts[0].ID || uploaded.Name != "aer-reports" || uploaded.Size == 0 {
+		t.Fatalf("unexpected upload %+v; server has %+v", uploaded, artifacts)
+	}
+	want := map[string]string{"aer-test-results.xml": "<testsuite/>", "html/index.html": "<html></html>"}
+	if !reflect.DeepEqual(artifacts[0].Files, want) {
+		t.Fatalf("unexpected artifact contents %v", artifacts[0].Files)
+	}
+	if artifacts[0].ExpiresAt == "" {
+		t.Fatal("retention was not sent")
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := []artifact.File{{Name: "results.xml", Path: writeFile(t, filepath.Join(dir, "results.xml"), "<testsuite/>")}}
+
+	cases := []struct {
+		name     string
+		setup    func(s *fakeartifacts.Server, c *artifact.Client)
+		artifact string
+		wantErr  string
+	}{
+		{
+			name: "reports service errors",
+			setup: func(s *fakeartifacts.Server, c *artifact.Client) {
+				s.Fail("CreateArtifact", http.StatusInternalServerError)
+			},
+			wantErr: "CreateArtifact: 500 Internal Server Error: injected failure (internal)",
+		},
+		{
+			name:    "reports blob storage errors without the signed URL",
+			setup:   func(s *fakeartifacts.Server, c *artifact.Client) { s.Fail("blob", http.StatusForbidden) },
+			wantErr: "upload artifact zip: unexpected HTTP status 403 Forbidden",
+		},
+		{
+			name:    "requires the runtime token",
+			setup:   func(s *fakeartifacts.Server, c *artifact.Client) { c.Token = "" },
+			wantErr: "ACTIONS_RUNTIME_TOKEN must be set",
+		},
+		{
+			name:    "requires the results scope",
+			setup:   func(s *fakeartifacts.Server, c *artifact.Client) { c.Token = fakeartifacts.Token("", "") },
+			wantErr: "no Actions.Results scope",
+		},
+		{
+			name:     "rejects invalid names",
+			artifact: "reports/shard-1",
+			wantErr:  `contains "/"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakeartifacts.New(t)
+			client := server.Client()
+			if tc.setup != nil {
+				tc.setup(server, client)
+			}
+			name := tc.artifact
+			if name == "" {
+				name = "aer-reports"
+			}
+			_, err := client.Upload(name, files, 0)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if strings.Contains(err.Error(), "sig=secret") {
+				t.Fatalf("error leaks the signed URL: %v", err)
+			}
+			if len(server.Artifacts()) != 0 {
+				t.Fatalf("failed upload was finalized: %+v", server.Artifacts())
+			}
+		})
+	}
+}
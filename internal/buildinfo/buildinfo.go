@@ -0,0 +1,135 @@
+// Package buildinfo describes the running aer binary for `aer version`, from
+// values set at link time and the build information embedded by the Go
+// toolchain.
+package buildinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// AerModule is the module that provides the aer runtime.
+const AerModule = "github.com/octoberswimmer/aer"
+
+// Set at link time, e.g. -ldflags "-X aer/internal/buildinfo.commit=abc123".
+// When empty they are read from the VCS stamp in the build information.
+var (
+	commit string
+	date   string
+)
+
+// Info describes a build.
+type Info struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	Date       string `json:"date,omitempty"`
+	GoVersion  string `json:"goVersion"`
+	Platform   string `json:"platform"`
+	AerVersion string `json:"aerVersion,omitempty"`
+	// Environment describes where aer is being run rather than the build.
+	Environment Environment `json:"environment"`
+}
+
+// Environment describes the caller's environment at the time Read was
+// called.
+type Environment struct {
+	// LicenseKeySet reports whether AER_LICENSE_KEY is set.
+	LicenseKeySet bool `json:"licenseKeySet"`
+}
+
+// Read returns the running binary's build information and the current
+// environment. version is the release version set at link time.
+func Read(version string) Info {
+	info := Info{
+		Version:     version,
+		Commit:      commit,
+		Date:        date,
+		GoVersion:   runtime.Version(),
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		Environment: Environment{LicenseKeySet: os.Getenv("AER_LICENSE_KEY") != ""},
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.merge(bi)
+	}
+	return info
+}
+
+// merge fills the fields not set at link time from the toolchain's build
+// information. The VCS stamp only marks the build modified when it also
+// supplied the commit.
+func (i *Info) merge(bi *debug.BuildInfo) {
+	linkedCommit := i.Commit != ""
+	if i.Version == "" || i.Version == "dev" {
+		if v := bi.Main.Version; v != "" && v != "(devel)" {
+			i.Version = v
+		}
+	}
+	if bi.GoVersion != "" {
+		i.GoVersion = bi.GoVersion
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if i.Commit == "" {
+				i.Commit = s.Value
+			}
+		case "vcs.time":
+			if i.Date == "" {
+				i.Date = s.Value
+			}
+		case "vcs.modified":
+			i.Modified = !linkedCommit && s.Value == "true"
+		}
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path != AerModule {
+			continue
+		}
+		i.AerVersion = dep.Version
+		if dep.Replace != nil {
+			i.AerVersion = strings.TrimSpace(fmt.Sprintf("%s => %s %s", dep.Version, dep.Replace.Path, dep.Replace.Version))
+		}
+	}
+}
+
+// String formats the build information for humans. The first line is always
+// "aer version <version>", which the installer parses.
+func (i Info) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "aer version %s\n", i.Version)
+	if i.Commit != "" {
+		commit := i.Commit
+		if i.Modified {
+			commit += " (modified)"
+		}
+		fmt.Fprintf(&sb, "  commit:      %s\n", commit)
+	}
+	if i.Date != "" {
+		fmt.Fprintf(&sb, "  built:       %s\n", i.Date)
+	}
+	fmt.Fprintf(&sb, "  go:          %s\n", i.GoVersion)
+	fmt.Fprintf(&sb, "  platform:    %s\n", i.Platform)
+	if i.AerVersion != "" {
+		fmt.Fprintf(&sb, "  aer runtime: %s %s\n", AerModule, i.AerVersion)
+	}
+	license := "AER_LICENSE_KEY not set (limited to 100 tests)"
+	if i.Environment.LicenseKeySet {
+		license = "AER_LICENSE_KEY set"
+	}
+	fmt.Fprintf(&sb, "  environment: %s\n", license)
+	return sb.String()
+}
+
+// JSON returns the build information as indented JSON.
+func (i Info) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
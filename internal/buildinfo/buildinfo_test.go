@@ -0,0 +1,85 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestMergeBuildInfo(t *testing.T) {
+	info := Info{Version: "dev", GoVersion: "go0", Platform: "linux/amd64"}
+	info.merge(&debug.BuildInfo{
+		GoVersion: "go1.25.3",
+		Main:      debug.Module{Path: "aer", Version: "v0.0.101"},
+		Deps: []*debug.Module{
+			{Path: "github.com/spf13/cobra", Version: "v1.10.1"},
+			{Path: AerModule, Version: "v0.0.101"},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123abcd"},
+			{Key: "vcs.time", Value: "2025-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	})
+
+	want := Info{
+		Version:    "v0.0.101",
+		Commit:     "0123abcd",
+		Modified:   true,
+		Date:       "2025-01-02T03:04:05Z",
+		GoVersion:  "go1.25.3",
+		Platform:   "linux/amd64",
+		AerVersion: "v0.0.101",
+	}
+	if info != want {
+		t.Fatalf("unexpected info:\n got %+v\nwant %+v", info, want)
+	}
+}
+
+func TestLinkTimeValuesWin(t *testing.T) {
+	info := Info{Version: "v1.0.0", Commit: "release-commit", Date: "2025-06-01T00:00:00Z"}
+	info.merge(&debug.BuildInfo{
+		Main:     debug.Module{Version: "(devel)"},
+		Deps:     []*debug.Module{{Path: AerModule, Version: "v0.0.100", Replace: &debug.Module{Path: "../aer"}}},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "other"}, {Key: "vcs.time", Value: "2020-01-01T00:00:00Z"}, {Key: "vcs.modified", Value: "true"}},
+	})
+	if info.Version != "v1.0.0" || info.Commit != "release-commit" || info.Date != "2025-06-01T00:00:00Z" || info.Modified {
+		t.Fatalf("link-time values were overridden: %+v", info)
+	}
+	if info.AerVersion != "v0.0.100 => ../aer" {
+		t.Fatalf("unexpected replaced module version %q", info.AerVersion)
+	}
+}
+
+func TestStringKeepsInstallerFormat(t *testing.T) {
+	info := Info{Version: "v0.0.101", Commit: "0123abcd", GoVersion: "go1.25.3", Platform: "darwin/arm64", AerVersion: "v0.0.101", Environment: Environment{LicenseKeySet: true}}
+	text := info.String()
+	// The installer and older scripts match this line.
+	if !regexp.MustCompile(`^aer version v0\.0\.101\n`).MatchString(text) {
+		t.Fatalf("first line changed:\n%s", text)
+	}
+	for _, want := range []string{"commit:      0123abcd", "platform:    darwin/arm64", "aer runtime: github.com/octoberswimmer/aer v0.0.101", "environment: AER_LICENSE_KEY set"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := Info{Version: "v0.0.101", GoVersion: "go1.25.3", Platform: "linux/arm64"}.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded["version"] != "v0.0.101" || decoded["platform"] != "linux/arm64" || decoded["environment"].(map[string]any)["licenseKeySet"] != false {
+		t.Fatalf("unexpected JSON %s", data)
+	}
+	if _, ok := decoded["commit"]; ok {
+		t.Fatalf("empty commit should be omitted: %s", data)
+	}
+}
@@ -0,0 +1,248 @@
+// Package fakeartifacts serves the GitHub Actions artifact service from
+// memory for tests of artifact uploads.
+package fakeartifacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"aer/internal/artifact"
+)
+
+// Backend IDs encoded in the token returned by Token.
+const (
+	RunBackendID = "c1a6a1b4-run"
+	JobBackendID = "7f3e9d2a-job"
+)
+
+// Artifact is an artifact uploaded to the fake.
+type Artifact struct {
+	ID        int64
+	Name      string
+	ExpiresAt string
+	// Files maps each path inside the artifact to its contents.
+	Files map[string]string
+}
+
+// Server is an httptest server implementing CreateArtifact, the signed blob
+// upload and FinalizeArtifact. It checks the backend IDs and that the
+// finalized size and digest match the uploaded zip.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	pending   map[string]*upload
+	artifacts []Artifact
+	failures  map[string]int
+}
+
+type upload struct {
+	expiresAt string
+	blob      []byte
+}
+
+// New starts a Server that is closed when the test ends.
+func New(t testing.TB) *Server {
+	s := &Server{pending: make(map[string]*upload), failures: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns an artifact.Client pointed at the server with a valid
+// runtime token.
+func (s *Server) Client() *artifact.Client {
+	return &artifact.Client{HTTP: s.Server.Client(), ResultsURL: s.URL, Token: Token(RunBackendID, JobBackendID)}
+}
+
+// Token returns an unsigned runtime token scoped to the run and job.
+func Token(run, job string) string {
+	claims, _ := json.Marshal(map[string]string{"scp": "Actions.ExampleScope Actions.Results:" + run + ":" + job})
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." + encode(claims) + ".sig"
+}
+
+// Fail makes calls to the Twirp method, or "blob" for the zip upload, fail
+// with status.
+func (s *Server) Fail(method string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = status
+}
+
+// Artifacts returns the finalized artifacts in upload order.
+func (s *Server) Artifacts() []Artifact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Artifact(nil), s.artifacts...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	method, ok := strings.CutPrefix(r.URL.Path, "/twirp/github.actions.results.api.v1.ArtifactService/")
+	if !ok {
+		method = "blob"
+	}
+	if status := s.failures[method]; status != 0 {
+		twirpError(w, status, "internal", "injected failure")
+		return
+	}
+
+	switch {
+	case method == "CreateArtifact" && r.Method == http.MethodPost:
+		s.create(w, r)
+	case method == "FinalizeArtifact" && r.Method == http.MethodPost:
+		s.finalize(w, r)
+	case strings.HasPrefix(r.URL.Path, "/blob/") && r.Method == http.MethodPut:
+		s.storeBlob(w, r)
+	default:
+		twirpError(w, http.StatusNotFound, "bad_route", "no handler for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+type request struct {
+	RunBackendID string `json:"workflow_run_backend_id"`
+	JobBackendID string `json:"workflow_job_run_backend_id"`
+	Name         string `json:"name"`
+	ExpiresAt    string `json:"expires_at"`
+	Version      int    `json:"version"`
+	Size         string `json:"size"`
+	Hash         string `json:"hash"`
+}
+
+// decode reads a Twirp request, writing an error and returning false when
+// it is malformed or not authorized for the fake's run and job.
+func decode(w http.ResponseWriter, r *http.Request) (request, bool) {
+	if r.Header.Get("Authorization") != "Bearer "+Token(RunBackendID, JobBackendID) {
+		twirpError(w, http.StatusUnauthorized, "unauthenticated", "invalid runtime token")
+		return request{}, false
+	}
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		twirpError(w, http.StatusBadRequest, "malformed", err.Error())
+		return request{}, false
+	}
+	if req.RunBackendID != RunBackendID || req.JobBackendID != JobBackendID {
+		twirpError(w, http.StatusBadRequest, "invalid_argument", "unknown backend IDs")
+		return request{}, false
+	}
+	return req, true
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	req, ok := decode(w, r)
+	if !ok {
+		return
+	}
+	if req.Version != 4 {
+		twirpError(w, http.StatusBadRequest, "invalid_argument", fmt.Sprintf("unsupported version %d", req.Version))
+		return
+	}
+	for _, a := range s.artifacts {
+		if a.Name == req.Name {
+			twirpError(w, http.StatusConflict, "already_exists", "an artifact with this name already exists on the workflow run")
+			return
+		}
+	}
+	s.pending[req.Name] = &upload{expiresAt: req.ExpiresAt}
+	writeJSON(w, map[string]any{"ok": true, "signed_upload_url": s.URL + "/blob/" + req.Name + "?sig=secret"})
+}
+
+func (s *Server) storeBlob(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/blob/")
+	pending := s.pending[name]
+	if pending == nil || r.URL.Query().Get("sig") != "secret" || r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+		http.Error(w, "AuthenticationFailed", http.StatusForbidden)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pending.blob = data
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) finalize(w http.ResponseWriter, r *http.Request) {
+	req, ok := decode(w, r)
+	if !ok {
+		return
+	}
+	pending := s.pending[req.Name]
+	if pending == nil || pending.blob == nil {
+		twirpError(w, http.StatusNotFound, "not_found", "artifact was not uploaded")
+		return
+	}
+	sum := sha256.Sum256(pending.blob)
+	if req.Size != strconv.Itoa(len(pending.blob)) || req.Hash != "sha256:"+hex.EncodeToString(sum[:]) {
+		twirpError(w, http.StatusBadRequest, "invalid_argument", "size or hash does not match the upload")
+		return
+	}
+	files, err := unzip(pending.blob)
+	if err != nil {
+		twirpError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+	delete(s.pending, req.Name)
+	id := int64(len(s.artifacts) + 1001)
+	s.artifacts = append(s.artifacts, Artifact{ID: id, Name: req.Name, ExpiresAt: pending.expiresAt, Files: files})
+	writeJSON(w, map[string]any{"ok": true, "artifact_id": strconv.FormatInt(id, 10)})
+}
+
+func unzip(data []byte) (map[string]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = string(body)
+	}
+	return files, nil
+}
+
+// Names returns the sorted paths inside an artifact.
+func (a Artifact) Names() []string {
+	names := make([]string, 0, len(a.Files))
+	for name := range a.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func twirpError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "msg": msg})
+}
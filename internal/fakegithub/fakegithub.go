@@ -0,0 +1,270 @@
+// Package fakegithub serves GitHub releases and release assets from memory
+// for tests of the action helpers.
+package fakegithub
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"aer/internal/github"
+	"aer/internal/release"
+	"aer/internal/sbom"
+)
+
+// Release is a release served by the fake.
+type Release struct {
+	Tag        string
+	Draft      bool
+	Prerelease bool
+	Assets     map[string][]byte
+}
+
+// Server is an httptest server implementing the release endpoints used by
+// github.Client. Releases are listed newest first, in the reverse of the
+// order they were added.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	releases map[string][]Release
+	statuses map[string]int
+	redirect bool
+	requests []string
+}
+
+// New starts a Server that is closed when the test ends.
+func New(t testing.TB) *Server {
+	s := &Server{releases: make(map[string][]Release), statuses: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a github.Client pointed at the server.
+func (s *Server) Client() *github.Client {
+	return &github.Client{HTTP: s.Server.Client(), APIURL: s.URL + "/api", ServerURL: s.URL}
+}
+
+// AddRelease publishes a release of repo ("owner/name").
+func (s *Server) AddRelease(repo string, rel Release) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releases[repo] = append(s.releases[repo], rel)
+}
+
+// FailPath makes requests whose path (without the query) ends in suffix
+// fail with status. A 403 also reports an exhausted rate limit.
+func (s *Server) FailPath(suffix string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[suffix] = status
+}
+
+// RedirectAssets makes asset downloads redirect to a separate storage path,
+// as github.com does.
+func (s *Server) RedirectAssets() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redirect = true
+}
+
+// Requests returns the paths requested so far, with their queries.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+
+	for suffix, status := range s.statuses {
+		if strings.HasSuffix(r.URL.Path, suffix) {
+			if status == http.StatusForbidden {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	// /api/repos/{owner}/{repo}/releases/latest
+	case len(parts) == 6 && parts[0] == "api" && parts[1] == "repos" && parts[4] == "releases" && parts[5] == "latest":
+		s.serveLatest(w, parts[2]+"/"+parts[3])
+	// /api/repos/{owner}/{repo}/releases
+	case len(parts) == 5 && parts[0] == "api" && parts[1] == "repos" && parts[4] == "releases":
+		s.serveList(w, parts[2]+"/"+parts[3])
+	// /{owner}/{repo}/releases/download/{tag}/{name}
+	case len(parts) == 6 && parts[2] == "releases" && parts[3] == "download":
+		if s.redirect {
+			http.Redirect(w, r, "/storage/"+strings.Join(parts, "/"), http.StatusFound)
+			return
+		}
+		s.serveAsset(w, parts[0]+"/"+parts[1], parts[4], parts[5])
+	// /storage/{owner}/{repo}/releases/download/{tag}/{name}
+	case len(parts) == 7 && parts[0] == "storage":
+		s.serveAsset(w, parts[1]+"/"+parts[2], parts[5], parts[6])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveLatest(w http.ResponseWriter, repo string) {
+	releases := s.releases[repo]
+	for i := len(releases) - 1; i >= 0; i-- {
+		if rel := releases[i]; !rel.Draft && !rel.Prerelease {
+			writeJSON(w, map[string]any{"tag_name": rel.Tag})
+			return
+		}
+	}
+	http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+}
+
+func (s *Server) serveList(w http.ResponseWriter, repo string) {
+	releases := s.releases[repo]
+	list := make([]map[string]any, 0, len(releases))
+	for i := len(releases) - 1; i >= 0; i-- {
+		rel := releases[i]
+		list = append(list, map[string]any{"tag_name": rel.Tag, "draft": rel.Draft, "prerelease": rel.Prerelease})
+	}
+	writeJSON(w, list)
+}
+
+func (s *Server) serveAsset(w http.ResponseWriter, repo, tag, name string) {
+	for _, rel := range s.releases[repo] {
+		if rel.Tag != tag || rel.Draft {
+			continue
+		}
+		if data, ok := rel.Assets[name]; ok {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(data)
+			return
+		}
+	}
+	http.Error(w, "Not Found", http.StatusNotFound)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// ZipFile is one entry of an archive built by Zip.
+type ZipFile struct {
+	Name string
+	Body string
+	Mode uint32 // Unix permission bits; zero leaves them unset
+}
+
+// Zip builds a zip archive, as published in releases.
+func Zip(t testing.TB, files ...ZipFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		header := &zip.FileHeader{Name: f.Name, Method: zip.Deflate}
+		if f.Mode != 0 {
+			header.SetMode(fs.FileMode(f.Mode))
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("create %s: %v", f.Name, err)
+		}
+		if _, err := w.Write([]byte(f.Body)); err != nil {
+			t.Fatalf("write %s: %v", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Checksums returns a SHA256SUMS file for assets, in `shasum -a 256`
+// format.
+func Checksums(assets map[string][]byte) []byte {
+	names := make([]string, 0, len(assets))
+	for name := range assets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		sum := sha256.Sum256(assets[name])
+		fmt.Fprintf(&buf, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	return buf.Bytes()
+}
+
+// Manifest returns a manifest-<version>.json describing the archives in
+// assets, and the SBOMs published alongside them, as the release tool does.
+func Manifest(t testing.TB, version string, assets map[string][]byte) []byte {
+	t.Helper()
+	manifest := release.Manifest{Version: version}
+	for name, data := range assets {
+		if isSBOM(name) {
+			continue
+		}
+		platform, _, ok := release.ParseArchiveName(name)
+		if !ok {
+			t.Fatalf("%s is not a release archive name", name)
+		}
+		goos, arch, _ := strings.Cut(platform, "_")
+		binary := "aer"
+		if goos == "windows" {
+			binary = "aer.exe"
+		}
+		sum := sha256.Sum256(data)
+		asset := release.ManifestAsset{
+			Name:     name,
+			Platform: platform,
+			OS:       goos,
+			Arch:     arch,
+			Binary:   binary,
+			Size:     int64(len(data)),
+			SHA256:   hex.EncodeToString(sum[:]),
+		}
+		for _, format := range sbom.Formats {
+			ext, _ := sbom.Extension(format)
+			sbomName := release.SBOMName(platform, version, ext)
+			if data, ok := assets[sbomName]; ok {
+				sum := sha256.Sum256(data)
+				asset.SBOMs = append(asset.SBOMs, release.SBOM{Format: format, Name: sbomName, SHA256: hex.EncodeToString(sum[:])})
+			}
+		}
+		manifest.Assets = append(manifest.Assets, asset)
+	}
+	sort.Slice(manifest.Assets, func(i, j int) bool {
+		return manifest.Assets[i].Platform < manifest.Assets[j].Platform
+	})
+	data, err := manifest.JSON()
+	if err != nil {
+		t.Fatalf("encode manifest: %v", err)
+	}
+	return data
+}
+
+func isSBOM(name string) bool {
+	for _, format := range sbom.Formats {
+		if ext, _ := sbom.Extension(format); strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+// Package ghenv writes step outputs, environment variables, PATH entries and
+// job summary content through the files GitHub Actions exposes to each step.
+package ghenv
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrUnset reports that the runner did not provide the file for a command,
+// as when a helper runs outside GitHub Actions.
+var ErrUnset = errors.New("not set")
+
+// Env is the set of step commands the helpers use. Files implements it for
+// the runner and Memory for tests.
+type Env interface {
+	// SetOutput sets a step output.
+	SetOutput(name, value string) error
+	// SetEnv sets an environment variable for later steps.
+	SetEnv(name, value string) error
+	// AddPath prepends dir to PATH for later steps.
+	AddPath(dir string) error
+	// AppendSummary appends Markdown to the job summary.
+	AppendSummary(markdown string) error
+}
+
+var (
+	_ Env = (*Files)(nil)
+	_ Env = (*Memory)(nil)
+)
+
+// Files writes to the command files named by the runner's environment.
+type Files struct {
+	Output  string // GITHUB_OUTPUT
+	Env     string // GITHUB_ENV
+	Path    string // GITHUB_PATH
+	Summary string // GITHUB_STEP_SUMMARY
+}
+
+// FromEnvironment returns the command files of the current step.
+func FromEnvironment() *Files {
+	return &Files{
+		Output:  os.Getenv("GITHUB_OUTPUT"),
+		Env:     os.Getenv("GITHUB_ENV"),
+		Path:    os.Getenv("GITHUB_PATH"),
+		Summary: os.Getenv("GITHUB_STEP_SUMMARY"),
+	}
+}
+
+func (f *Files) SetOutput(name, value string) error {
+	record, err := formatRecord(name, value)
+	if err != nil {
+		return err
+	}
+	return appendFile("GITHUB_OUTPUT", f.Output, record)
+}
+
+func (f *Files) SetEnv(name, value string) error {
+	record, err := formatRecord(name, value)
+	if err != nil {
+		return err
+	}
+	return appendFile("GITHUB_ENV", f.Env, record)
+}
+
+func (f *Files) AddPath(dir string) error {
+	if dir == "" || strings.ContainsAny(dir, "\r\n") {
+		return fmt.Errorf("invalid PATH entry %q", dir)
+	}
+	return appendFile("GITHUB_PATH", f.Path, dir+"\n")
+}
+
+func (f *Files) AppendSummary(markdown string) error {
+	return appendFile("GITHUB_STEP_SUMMARY", f.Summary, markdown)
+}
+
+// appendMu serializes appends within the process. Each record is also
+// written with a single write to an O_APPEND file, so records from other
+// processes sharing the file are never interleaved.
+var appendMu sync.Mutex
+
+func appendFile(variable, path, data string) error {
+	if path == "" {
+		return fmt.Errorf("%s is %w", variable, ErrUnset)
+	}
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", variable, err)
+	}
+	if _, err := file.WriteString(data); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", variable, err)
+	}
+	return file.Close()
+}
+
+// formatRecord encodes name and value for GITHUB_OUTPUT or GITHUB_ENV,
+// using the heredoc form with a random delimiter for multiline values.
+func formatRecord(name, value string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "=\r\n") {
+		return "", fmt.Errorf("invalid name %q", name)
+	}
+	if !strings.ContainsAny(value, "\r\n") {
+		return name + "=" + value + "\n", nil
+	}
+	for {
+		delimiter, err := newDelimiter()
+		if err != nil {
+			return "", err
+		}
+		if !strings.Contains(value, delimiter) {
+			return fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter), nil
+		}
+	}
+}
+
+func newDelimiter() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "ghadelimiter_" + hex.EncodeToString(buf), nil
+}
+
+// ParseRecords decodes the contents of a GITHUB_OUTPUT or GITHUB_ENV file,
+// including heredoc values. Later records override earlier ones, as on the
+// runner.
+func ParseRecords(data string) (map[string]string, error) {
+	records := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		if name, value, ok := strings.Cut(line, "="); ok && !strings.Contains(name, "<<") {
+			records[name] = value
+			continue
+		}
+		name, delimiter, ok := strings.Cut(line, "<<")
+		if !ok || name == "" || delimiter == "" {
+			return nil, fmt.Errorf("line %d: invalid record %q", i+1, line)
+		}
+		var value []string
+		closed := false
+		for i++; i < len(lines); i++ {
+			if lines[i] == delimiter {
+				closed = true
+				break
+			}
+			value = append(value, lines[i])
+		}
+		if !closed {
+			return nil, fmt.Errorf("%s: missing delimiter %s", name, delimiter)
+		}
+		records[name] = strings.Join(value, "\n")
+	}
+	return records, nil
+}
@@ -0,0 +1,121 @@
+package ghenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func tempFiles(t *testing.T) *Files {
+	t.Helper()
+	dir := t.TempDir()
+	return &Files{
+		Output:  filepath.Join(dir, "output"),
+		Env:     filepath.Join(dir, "env"),
+		Path:    filepath.Join(dir, "path"),
+		Summary: filepath.Join(dir, "summary"),
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestFilesRoundTrip(t *testing.T) {
+	files := tempFiles(t)
+	if err := files.SetOutput("version", "v1.2.3"); err != nil {
+		t.Fatalf("SetOutput: %v", err)
+	}
+	if err := files.SetOutput("classes", "FooTest\nBarTest"); err != nil {
+		t.Fatalf("SetOutput multiline: %v", err)
+	}
+	if err := files.SetEnv("AER_HOME", "/opt/aer"); err != nil {
+		t.Fatalf("SetEnv: %v", err)
+	}
+	if err := files.AddPath("/opt/aer/bin"); err != nil {
+		t.Fatalf("AddPath: %v", err)
+	}
+	if err := files.AppendSummary("## Results\n"); err != nil {
+		t.Fatalf("AppendSummary: %v", err)
+	}
+
+	raw := readFile(t, files.Output)
+	if !strings.Contains(raw, "version=v1.2.3\n") || !strings.Contains(raw, "classes<<ghadelimiter_") {
+		t.Fatalf("unexpected output file:\n%s", raw)
+	}
+	outputs, err := ParseRecords(raw)
+	if err != nil {
+		t.Fatalf("ParseRecords: %v", err)
+	}
+	if outputs["version"] != "v1.2.3" || outputs["classes"] != "FooTest\nBarTest" {
+		t.Fatalf("unexpected outputs %q", outputs)
+	}
+	if got := readFile(t, files.Env); got != "AER_HOME=/opt/aer\n" {
+		t.Fatalf("unexpected env file %q", got)
+	}
+	if got := readFile(t, files.Path); got != "/opt/aer/bin\n" {
+		t.Fatalf("unexpected path file %q", got)
+	}
+	if got := readFile(t, files.Summary); got != "## Results\n" {
+		t.Fatalf("unexpected summary %q", got)
+	}
+}
+
+func TestFilesRejectInvalidRecords(t *testing.T) {
+	files := tempFiles(t)
+	for _, name := range []string{"", "a=b", "a\nb"} {
+		if err := files.SetOutput(name, "x"); err == nil {
+			t.Fatalf("expected name %q to be rejected", name)
+		}
+	}
+	if err := files.AddPath("/bin\n/evil"); err == nil {
+		t.Fatal("expected multiline PATH entry to be rejected")
+	}
+}
+
+func TestFilesUnset(t *testing.T) {
+	err := (&Files{}).SetOutput("version", "v1")
+	if !errors.Is(err, ErrUnset) || err.Error() != "GITHUB_OUTPUT is not set" {
+		t.Fatalf("expected unset error, got %v", err)
+	}
+}
+
+func TestFilesConcurrentAppends(t *testing.T) {
+	files := tempFiles(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := files.SetOutput("lines", "one\ntwo\nthree"); err != nil {
+				t.Errorf("SetOutput: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := ParseRecords(readFile(t, files.Output)); err != nil {
+		t.Fatalf("interleaved records: %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	env := NewMemory()
+	env.SetOutput("count", "3")
+	env.AddPath("/opt/aer")
+	env.AppendSummary("ok")
+	if env.Outputs["count"] != "3" || len(env.Path) != 1 || env.Summary.String() != "ok" {
+		t.Fatalf("unexpected state %+v", env)
+	}
+	if err := env.SetOutput("a=b", "x"); err == nil {
+		t.Fatal("expected invalid name to be rejected")
+	}
+}
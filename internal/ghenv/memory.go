@@ -0,0 +1,58 @@
+package ghenv
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Memory records step commands in memory, for tests.
+type Memory struct {
+	mu      sync.Mutex
+	Outputs map[string]string
+	Env     map[string]string
+	Path    []string
+	Summary strings.Builder
+}
+
+// NewMemory returns an empty Memory.
+func NewMemory() *Memory {
+	return &Memory{Outputs: make(map[string]string), Env: make(map[string]string)}
+}
+
+func (m *Memory) SetOutput(name, value string) error {
+	if _, err := formatRecord(name, value); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Outputs[name] = value
+	return nil
+}
+
+func (m *Memory) SetEnv(name, value string) error {
+	if _, err := formatRecord(name, value); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Env[name] = value
+	return nil
+}
+
+func (m *Memory) AddPath(dir string) error {
+	if dir == "" || strings.ContainsAny(dir, "\r\n") {
+		return fmt.Errorf("invalid PATH entry %q", dir)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Path = append(m.Path, dir)
+	return nil
+}
+
+func (m *Memory) AppendSummary(markdown string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Summary.WriteString(markdown)
+	return nil
+}
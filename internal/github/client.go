@@ -0,0 +1,188 @@
+// Package github is a minimal client for the GitHub release endpoints the
+// action helpers use. Its HTTP client and base URLs are injectable so the
+// helpers can be pointed at GitHub Enterprise Server or a test server.
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"aer/internal/actionlog"
+)
+
+// ErrNotFound reports a release or asset that does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Client fetches releases and their assets.
+type Client struct {
+	HTTP      *http.Client
+	APIURL    string // REST API root, e.g. https://api.github.com
+	ServerURL string // web root that serves release downloads, e.g. https://github.com
+}
+
+// NewClient returns a Client for the GitHub instance the workflow runs on,
+// as named by GITHUB_API_URL and GITHUB_SERVER_URL, defaulting to
+// github.com.
+func NewClient() *Client {
+	return &Client{
+		HTTP:      http.DefaultClient,
+		APIURL:    envOr("GITHUB_API_URL", "https://api.github.com"),
+		ServerURL: envOr("GITHUB_SERVER_URL", "https://github.com"),
+	}
+}
+
+// AssetURL returns the download URL of a release asset.
+func (c *Client) AssetURL(repo, version, name string) string {
+	return fmt.Sprintf("%s/%s/releases/download/%s/%s", strings.TrimRight(c.ServerURL, "/"), repo, version, name)
+}
+
+// DownloadAsset writes a release asset to w. A missing asset is reported as
+// an error wrapping ErrNotFound.
+func (c *Client) DownloadAsset(repo, version, name string, w io.Writer) error {
+	resp, err := c.get(c.AssetURL(repo, version, name), "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("download %s: %w", name, err)
+	}
+	return nil
+}
+
+// LatestTag returns the tag of the repository's latest release, falling back
+// to the newest published release when none is marked latest (for example
+// when every release is a pre-release).
+func (c *Client) LatestTag(repo string) (string, error) {
+	resp, err := c.get(c.apiURL("repos/%s/releases/latest", repo), "application/vnd.github+json")
+	if errors.Is(err, ErrNotFound) {
+		return c.latestFromList(repo)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var payload struct {
+		Tag string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", err
+	}
+	if payload.Tag == "" {
+		return "", fmt.Errorf("latest release response missing tag_name")
+	}
+	return payload.Tag, nil
+}
+
+func (c *Client) latestFromList(repo string) (string, error) {
+	releases, err := c.listReleases(repo, 1)
+	if err != nil {
+		return "", err
+	}
+	for _, rel := range releases {
+		if rel.Draft {
+			continue
+		}
+		if rel.Tag != "" {
+			return rel.Tag, nil
+		}
+	}
+	return "", fmt.Errorf("no published releases found")
+}
+
+// Release summarizes a published release.
+type Release struct {
+	Tag        string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// Releases returns the newest releases of repo, newest first, omitting
+// drafts.
+func (c *Client) Releases(repo string) ([]Release, error) {
+	releases, err := c.listReleases(repo, 100)
+	if err != nil {
+		return nil, err
+	}
+	published := releases[:0]
+	for _, rel := range releases {
+		if !rel.Draft && rel.Tag != "" {
+			published = append(published, rel)
+		}
+	}
+	return published, nil
+}
+
+func (c *Client) listReleases(repo string, perPage int) ([]Release, error) {
+	resp, err := c.get(c.apiURL("repos/%s/releases?per_page=%d", repo, perPage), "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var releases []Release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+func (c *Client) apiURL(format string, args ...any) string {
+	return strings.TrimRight(c.APIURL, "/") + "/" + fmt.Sprintf(format, args...)
+}
+
+// get issues a GET request and returns the response when it succeeds. The
+// caller closes the body.
+func (c *Client) get(url, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	actionlog.Debugf("GET %s", url)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	actionlog.Debugf("GET %s: %s", url, resp.Status)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", url, ErrNotFound)
+	case resp.StatusCode >= 400:
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected HTTP status %s%s", url, resp.Status, rateLimitHint(resp))
+	}
+	return resp, nil
+}
+
+// rateLimitHint explains a 403 or 429 caused by the API rate limit, which
+// is the most common reason unauthenticated requests from shared runners
+// fail.
+func rateLimitHint(resp *http.Response) string {
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return " (API rate limit exceeded; pin a version to avoid the lookup)"
+	}
+	return ""
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
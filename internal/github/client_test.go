@@ -0,0 +1,109 @@
+package github_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"aer/internal/fakegithub"
+	"aer/internal/github"
+)
+
+func TestLatestTag(t *testing.T) {
+	cases := []struct {
+		name     string
+		releases []fakegithub.Release
+		fail     map[string]int
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "latest release",
+			releases: []fakegithub.Release{{Tag: "v1.0.0"}, {Tag: "v1.1.0"}},
+			want:     "v1.1.0",
+		},
+		{
+			name:     "falls back to the newest pre-release",
+			releases: []fakegithub.Release{{Tag: "v2.0.0-rc.1", Prerelease: true}},
+			want:     "v2.0.0-rc.1",
+		},
+		{
+			name:     "skips drafts in the fallback",
+			releases: []fakegithub.Release{{Tag: "v2.0.0-rc.1", Prerelease: true}, {Tag: "v2.0.0-rc.2", Draft: true}},
+			want:     "v2.0.0-rc.1",
+		},
+		{
+			name:    "no releases",
+			wantErr: "no published releases found",
+		},
+		{
+			name:     "rate limited",
+			releases: []fakegithub.Release{{Tag: "v1.0.0"}},
+			fail:     map[string]int{"/releases/latest": http.StatusForbidden},
+			wantErr:  "API rate limit exceeded",
+		},
+		{
+			name:     "server error",
+			releases: []fakegithub.Release{{Tag: "v1.0.0"}},
+			fail:     map[string]int{"/releases/latest": http.StatusBadGateway},
+			wantErr:  "502",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakegithub.New(t)
+			for _, rel := range tc.releases {
+				server.AddRelease("octoberswimmer/aer-dist", rel)
+			}
+			for suffix, status := range tc.fail {
+				server.FailPath(suffix, status)
+			}
+
+			tag, err := server.Client().LatestTag("octoberswimmer/aer-dist")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil || tag != tc.want {
+				t.Fatalf("expected %s, got %q (%v)", tc.want, tag, err)
+			}
+		})
+	}
+}
+
+func TestDownloadAsset(t *testing.T) {
+	server := fakegithub.New(t)
+	server.AddRelease("octoberswimmer/aer-dist", fakegithub.Release{Tag: "v1", Assets: map[string][]byte{"SHA256SUMS-v1": []byte("sums\n")}})
+	server.RedirectAssets()
+	client := server.Client()
+
+	var buf bytes.Buffer
+	if err := client.DownloadAsset("octoberswimmer/aer-dist", "v1", "SHA256SUMS-v1", &buf); err != nil {
+		t.Fatalf("DownloadAsset: %v", err)
+	}
+	if buf.String() != "sums\n" {
+		t.Fatalf("unexpected asset %q", buf.String())
+	}
+
+	err := client.DownloadAsset("octoberswimmer/aer-dist", "v1", "missing.zip", &buf)
+	if !errors.Is(err, github.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNewClientHonorsEnterpriseURLs(t *testing.T) {
+	t.Setenv("GITHUB_SERVER_URL", "https://ghe.example.com")
+	t.Setenv("GITHUB_API_URL", "https://ghe.example.com/api/v3")
+	client := github.NewClient()
+	if got := client.AssetURL("o/r", "v1", "a.zip"); got != "https://ghe.example.com/o/r/releases/download/v1/a.zip" {
+		t.Fatalf("unexpected asset URL %s", got)
+	}
+	if client.APIURL != "https://ghe.example.com/api/v3" {
+		t.Fatalf("unexpected API URL %s", client.APIURL)
+	}
+}
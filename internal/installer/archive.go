@@ -0,0 +1,103 @@
+package installer
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractBinary extracts the file named binaryName from a release archive
+// into destDir and returns its path.
+func ExtractBinary(archivePath, binaryName, destDir string) (string, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(file.Name, "/")
+		if strings.HasSuffix(name, binaryName) {
+			extracted := filepath.Join(destDir, binaryName)
+			if err := extractZipFile(file, extracted); err != nil {
+				return "", err
+			}
+			return extracted, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in archive", binaryName)
+}
+
+func extractZipFile(file *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, rc); err != nil {
+		return err
+	}
+
+	if mode := file.Mode(); mode != 0 {
+		if err := out.Chmod(mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveFile moves src to dest, replacing dest.
+func MoveFile(src, dest string) error {
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+	// Fall back to copy if rename fails (e.g. cross-device move).
+	if err := CopyFile(src, dest); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// CopyFile copies src to dest, creating dest's directory.
+func CopyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return nil
+}
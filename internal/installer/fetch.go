@@ -0,0 +1,305 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"aer/internal/release"
+	"aer/internal/sbom"
+)
+
+// Logger receives progress messages. *actionlog.Logger satisfies it.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warningf(format string, args ...any)
+}
+
+// Request describes the release archive to fetch and how to verify it.
+type Request struct {
+	Source     Source
+	Version    string
+	Candidates []Candidate
+	// TmpDir receives the downloaded assets.
+	TmpDir string
+	// RequireChecksums refuses a release publishing neither a manifest nor
+	// SHA256SUMS-<version>.
+	RequireChecksums bool
+	// PublicKey, when set, must have signed SHA256SUMS-<version>;
+	// RequireSignature makes a missing signature an error.
+	PublicKey        release.PublicKey
+	RequireSignature bool
+	// Locked are the digests recorded in Lockfile, which the archive must
+	// also match.
+	Locked   release.Checksums
+	Lockfile string
+	Log      Logger
+}
+
+// Archive is a downloaded and verified release archive.
+type Archive struct {
+	Candidate Candidate
+	Name      string
+	Path      string
+	// Checksums are the verified release digests, nil when the release
+	// publishes none.
+	Checksums release.Checksums
+	// Asset is the archive's manifest entry, nil without a manifest.
+	Asset *release.ManifestAsset
+}
+
+// Fetch downloads the archive for the first available candidate and
+// verifies it against the release manifest or checksums, their signature and
+// the lock file.
+func Fetch(req Request) (Archive, error) {
+	source, version, log := req.Source, req.Version, req.Log
+
+	manifest, err := fetchManifest(source, version, req.TmpDir)
+	var notFound *NotFoundError
+	switch {
+	case err == nil:
+		log.Infof("Using %s from %s", release.ManifestName(version), source)
+	case errors.As(err, &notFound):
+		log.Debugf("%s has no %s; using archive names and %s", source, release.ManifestName(version), release.ChecksumsName(version))
+	default:
+		return Archive{}, fmt.Errorf("read release manifest: %w", err)
+	}
+
+	checksums, err := verifiedChecksums(req, manifest)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	candidates := req.Candidates
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.ArchiveName(version)
+	}
+	if manifest != nil {
+		candidates, names, err = manifestCandidates(*manifest, candidates, log)
+		if err != nil {
+			return Archive{}, err
+		}
+	}
+
+	var selected Candidate
+	var archiveName, archivePath string
+	for i, c := range candidates {
+		archiveName = names[i]
+		archivePath = filepath.Join(req.TmpDir, archiveName)
+		err = source.Fetch(archiveName, archivePath)
+		if err == nil {
+			selected = c
+			break
+		}
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) || i == len(candidates)-1 {
+			return Archive{}, fmt.Errorf("fetch archive: %w", err)
+		}
+		log.Infof("%s is not available from %s; trying the next compatible target", archiveName, source)
+	}
+	if checksums != nil {
+		if err := checksums.Verify(archivePath, archiveName); err != nil {
+			return Archive{}, fmt.Errorf("verify archive: %w", err)
+		}
+		log.Infof("Verified SHA-256 checksum of %s", archiveName)
+	}
+	if req.Locked != nil {
+		if err := req.Locked.Verify(archivePath, archiveName); err != nil {
+			return Archive{}, fmt.Errorf("verify archive against %s: %w", req.Lockfile, err)
+		}
+		log.Infof("Verified %s against %s", archiveName, req.Lockfile)
+	}
+	if selected.Emulation != "" {
+		log.Infof("Using the %s build via %s", selected.Target, selected.Emulation)
+	}
+	fetched := Archive{Candidate: selected, Name: archiveName, Path: archivePath, Checksums: checksums}
+	if manifest != nil {
+		for i := range manifest.Assets {
+			if manifest.Assets[i].Name == archiveName {
+				fetched.Asset = &manifest.Assets[i]
+			}
+		}
+	}
+	return fetched, nil
+}
+
+// FetchSBOM downloads the SBOM in format published for the fetched archive
+// to dest, verifying it when the release checksums list it. A release
+// without the SBOM only logs a warning, and the returned path is empty.
+func FetchSBOM(req Request, fetched Archive, format, dest string) (string, error) {
+	log := req.Log
+	ext, err := sbom.Extension(format)
+	if err != nil {
+		return "", err
+	}
+	name := release.SBOMName(fetched.Candidate.Asset, req.Version, ext)
+	if fetched.Asset != nil {
+		published, ok := fetched.Asset.SBOM(format)
+		if !ok {
+			log.Warningf("%s lists no %s SBOM for %s; skipping the SBOM", release.ManifestName(req.Version), format, fetched.Name)
+			return "", nil
+		}
+		name = published.Name
+	}
+
+	path := filepath.Join(req.TmpDir, name)
+	if err := req.Source.Fetch(name, path); err != nil {
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			log.Warningf("%s has no %s; skipping the SBOM", req.Source, name)
+			return "", nil
+		}
+		return "", fmt.Errorf("fetch SBOM: %w", err)
+	}
+	if _, listed := fetched.Checksums[name]; listed {
+		if err := fetched.Checksums.Verify(path, name); err != nil {
+			return "", fmt.Errorf("verify SBOM: %w", err)
+		}
+		log.Infof("Verified SHA-256 checksum of %s", name)
+	} else if fetched.Checksums != nil {
+		log.Warningf("%s is not listed in the release checksums; installing it unverified", name)
+	}
+	if err := MoveFile(path, dest); err != nil {
+		return "", fmt.Errorf("move SBOM: %w", err)
+	}
+	log.Infof("Installed %s SBOM to %s", format, dest)
+	return dest, nil
+}
+
+// verifiedChecksums returns the digests the archive is checked against. The
+// manifest's digests are used when there is one; SHA256SUMS-<version> is
+// still read when it is signed, and the manifest must then agree with it. A
+// missing signature is only an error when one is required. It returns nil
+// when the release publishes neither and checksums are optional.
+func verifiedChecksums(req Request, manifest *release.Manifest) (release.Checksums, error) {
+	source, version, log := req.Source, req.Version, req.Log
+
+	var signature []byte
+	if req.PublicKey != nil {
+		var err error
+		signature, err = fetchSignature(source, req.PublicKey, version, req.TmpDir)
+		var notFound *NotFoundError
+		switch {
+		case err == nil:
+		case errors.As(err, &notFound) && !req.RequireSignature:
+			log.Warningf("%s has no %s; the checksums are unsigned", source, req.PublicKey.SignatureName(version))
+		default:
+			return nil, fmt.Errorf("verify checksums signature: %w", err)
+		}
+	}
+	if manifest != nil && signature == nil {
+		return manifest.Checksums(), nil
+	}
+
+	checksums, data, err := fetchChecksums(source, version, req.TmpDir)
+	if err != nil {
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) || signature != nil || req.RequireSignature || (req.RequireChecksums && manifest == nil) {
+			return nil, fmt.Errorf("read release checksums: %w", err)
+		}
+		log.Warningf("%s has no %s; skipping checksum verification", source, release.ChecksumsName(version))
+		return nil, nil
+	}
+	if signature != nil {
+		if err := req.PublicKey.Verify(data, signature); err != nil {
+			return nil, fmt.Errorf("verify checksums signature: %s: %w", req.PublicKey.SignatureName(version), err)
+		}
+		log.Infof("Verified signature of %s with %s", release.ChecksumsName(version), req.PublicKey)
+	}
+	if manifest == nil {
+		return checksums, nil
+	}
+	digests := manifest.Checksums()
+	for name, digest := range digests {
+		if checksums[name] != digest {
+			return nil, fmt.Errorf("%s and %s disagree about the digest of %s", release.ManifestName(version), release.ChecksumsName(version), name)
+		}
+	}
+	return digests, nil
+}
+
+// fetchManifest reads and parses the manifest-<version>.json published with
+// a release.
+func fetchManifest(source Source, version, tmpDir string) (*release.Manifest, error) {
+	name := release.ManifestName(version)
+	path := filepath.Join(tmpDir, name)
+	if err := source.Fetch(name, path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := release.ParseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Version != version {
+		return nil, fmt.Errorf("%s describes version %s", name, manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// manifestCandidates keeps the candidates the manifest publishes a build for,
+// in order, and returns the archive name of each. The manifest's binary name
+// replaces the installer's default.
+func manifestCandidates(manifest release.Manifest, candidates []Candidate, log Logger) ([]Candidate, []string, error) {
+	var kept []Candidate
+	var names []string
+	for _, c := range candidates {
+		for _, asset := range manifest.Assets {
+			if asset.Platform != c.Asset {
+				continue
+			}
+			if asset.Binary != "" {
+				c.Binary = asset.Binary
+			}
+			if asset.MinOS != "" {
+				log.Debugf("%s requires %s %s or later", asset.Name, asset.OS, asset.MinOS)
+			}
+			kept = append(kept, c)
+			names = append(names, asset.Name)
+			break
+		}
+	}
+	if len(kept) == 0 {
+		platforms := make([]string, 0, len(manifest.Assets))
+		for _, asset := range manifest.Assets {
+			platforms = append(platforms, asset.Platform)
+		}
+		return nil, nil, fmt.Errorf("%s has no build for this runner (%s); it publishes %s",
+			release.ManifestName(manifest.Version), candidates[0].Target, strings.Join(platforms, ", "))
+	}
+	return kept, names, nil
+}
+
+// fetchChecksums reads the SHA256SUMS-<version> file from the source,
+// returning the parsed digests and the raw file for signature checks.
+func fetchChecksums(source Source, version, tmpDir string) (release.Checksums, []byte, error) {
+	name := release.ChecksumsName(version)
+	path := filepath.Join(tmpDir, name)
+	if err := source.Fetch(name, path); err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	sums, err := release.ParseChecksums(data)
+	return sums, data, err
+}
+
+// fetchSignature reads the detached signature key checks for the version's
+// checksums file. A missing signature is reported as a *NotFoundError.
+func fetchSignature(source Source, key release.PublicKey, version, tmpDir string) ([]byte, error) {
+	name := key.SignatureName(version)
+	path := filepath.Join(tmpDir, name)
+	if err := source.Fetch(name, path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,185 @@
+// Package installer selects the release archive for a host and extracts and
+// verifies the aer binary in it. It is shared by the GitHub Action's install
+// helper and `aer self-update`.
+package installer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Supported C library variants on Linux. Published builds are statically
+// linked (CGO_ENABLED=0), so both use the same asset today.
+const (
+	LibcGNU  = "gnu"
+	LibcMusl = "musl"
+)
+
+// Target describes one platform the installer can serve and the release asset
+// that provides it.
+type Target struct {
+	OS     string // normalized operating system (GOOS)
+	Arch   string // normalized architecture (GOARCH)
+	Libc   string // C library variant; empty where it does not apply
+	Asset  string // platform portion of the archive name
+	Format string // archive format and extension
+	Binary string // executable name inside the archive
+}
+
+// targets lists every supported platform. Adding a platform is a one-line
+// change here.
+var targets = []Target{
+	{OS: "linux", Arch: "amd64", Libc: LibcGNU, Asset: "linux_amd64", Format: "zip", Binary: "aer"},
+	{OS: "linux", Arch: "amd64", Libc: LibcMusl, Asset: "linux_amd64", Format: "zip", Binary: "aer"},
+	{OS: "linux", Arch: "arm64", Libc: LibcGNU, Asset: "linux_arm64", Format: "zip", Binary: "aer"},
+	{OS: "linux", Arch: "arm64", Libc: LibcMusl, Asset: "linux_arm64", Format: "zip", Binary: "aer"},
+	{OS: "darwin", Arch: "amd64", Asset: "darwin_amd64", Format: "zip", Binary: "aer"},
+	{OS: "darwin", Arch: "arm64", Asset: "darwin_arm64", Format: "zip", Binary: "aer"},
+	{OS: "windows", Arch: "amd64", Asset: "windows_amd64", Format: "zip", Binary: "aer.exe"},
+}
+
+// emulation describes a host that can run binaries built for another
+// architecture.
+type emulation struct {
+	OS   string
+	Arch string
+	Runs string
+	Via  string
+}
+
+// emulations are tried, in order, when no native build is published for the
+// host or a release is missing the native asset.
+var emulations = []emulation{
+	{OS: "windows", Arch: "arm64", Runs: "amd64", Via: "Windows x64 emulation"},
+	{OS: "darwin", Arch: "arm64", Runs: "amd64", Via: "Rosetta 2"},
+}
+
+// ArchiveName returns the release asset name for this target.
+func (t Target) ArchiveName(version string) string {
+	return fmt.Sprintf("aer_%s_%s.%s", t.Asset, version, t.Format)
+}
+
+func (t Target) String() string {
+	if t.Libc != "" {
+		return fmt.Sprintf("%s/%s (%s)", t.OS, t.Arch, t.Libc)
+	}
+	return fmt.Sprintf("%s/%s", t.OS, t.Arch)
+}
+
+// Candidate is a target selected for a host, noting any emulation required.
+type Candidate struct {
+	Target
+	Emulation string
+}
+
+// ResolveTargets returns the targets that can run on the given host, native
+// build first followed by emulated fallbacks.
+func ResolveTargets(osName, arch, libc string) ([]Candidate, error) {
+	platformKey, err := normalizeOS(osName)
+	if err != nil {
+		return nil, err
+	}
+	cpuKey, err := normalizeArch(arch)
+	if err != nil {
+		return nil, err
+	}
+	if platformKey != "linux" {
+		libc = ""
+	} else if libc == "" {
+		libc = LibcGNU
+	}
+
+	var candidates []Candidate
+	if t, ok := lookupTarget(platformKey, cpuKey, libc); ok {
+		candidates = append(candidates, Candidate{Target: t})
+	}
+	for _, emu := range emulations {
+		if emu.OS != platformKey || emu.Arch != cpuKey {
+			continue
+		}
+		if t, ok := lookupTarget(platformKey, emu.Runs, libc); ok {
+			candidates = append(candidates, Candidate{Target: t, Emulation: emu.Via})
+		}
+	}
+
+	if len(candidates) == 0 {
+		host := Target{OS: platformKey, Arch: cpuKey, Libc: libc}
+		return nil, fmt.Errorf("no published aer build for %s; supported targets: %s", host, strings.Join(supportedTargets(), ", "))
+	}
+	return candidates, nil
+}
+
+func lookupTarget(osName, arch, libc string) (Target, bool) {
+	for _, t := range targets {
+		if t.OS == osName && t.Arch == arch && t.Libc == libc {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
+
+// supportedTargets lists every host the installer accepts, including those
+// served through emulation.
+func supportedTargets() []string {
+	var names []string
+	for _, t := range targets {
+		names = append(names, t.String())
+	}
+	for _, emu := range emulations {
+		names = append(names, fmt.Sprintf("%s/%s (via %s)", emu.OS, emu.Arch, emu.Via))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func normalizeOS(osName string) (string, error) {
+	switch strings.ToLower(osName) {
+	case "linux":
+		return "linux", nil
+	case "macos", "darwin":
+		return "darwin", nil
+	case "windows":
+		return "windows", nil
+	default:
+		return "", fmt.Errorf("unsupported operating system: %q", osName)
+	}
+}
+
+func normalizeArch(arch string) (string, error) {
+	switch strings.ToLower(arch) {
+	case "amd64", "x86_64", "x64":
+		return "amd64", nil
+	case "arm64", "aarch64":
+		return "arm64", nil
+	default:
+		return "", fmt.Errorf("unsupported architecture: %q", arch)
+	}
+}
+
+// NormalizeLibc maps a libc input ("gnu", "glibc", "musl" or "alpine", in any
+// case) to LibcGNU or LibcMusl. An empty input returns "", leaving the
+// variant unspecified.
+func NormalizeLibc(libc string) (string, error) {
+	switch strings.ToLower(libc) {
+	case "":
+		return "", nil
+	case "gnu", "glibc":
+		return LibcGNU, nil
+	case "musl", "alpine":
+		return LibcMusl, nil
+	default:
+		return "", fmt.Errorf("unsupported libc variant: %q", libc)
+	}
+}
+
+// DetectLibc reports musl when the host's dynamic loader is musl's, as on
+// Alpine. It only inspects the local filesystem, so it is only meaningful when
+// the installer runs on the target host.
+func DetectLibc() string {
+	if matches, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(matches) > 0 {
+		return LibcMusl
+	}
+	return LibcGNU
+}
@@ -0,0 +1,95 @@
+package installer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveTargets(t *testing.T) {
+	cases := []struct {
+		os, arch, libc string
+		want           []string // archive names, in fallback order
+		emulated       string
+	}{
+		{"Linux", "X64", "", []string{"aer_linux_amd64_v1.zip"}, ""},
+		{"linux", "aarch64", "musl", []string{"aer_linux_arm64_v1.zip"}, ""},
+		{"macOS", "ARM64", "", []string{"aer_darwin_arm64_v1.zip", "aer_darwin_amd64_v1.zip"}, "Rosetta 2"},
+		{"darwin", "x86_64", "", []string{"aer_darwin_amd64_v1.zip"}, ""},
+		{"Windows", "X64", "", []string{"aer_windows_amd64_v1.zip"}, ""},
+		{"Windows", "ARM64", "", []string{"aer_windows_amd64_v1.zip"}, "Windows x64 emulation"},
+	}
+
+	for _, tc := range cases {
+		candidates, err := ResolveTargets(tc.os, tc.arch, tc.libc)
+		if err != nil {
+			t.Fatalf("%s/%s: %v", tc.os, tc.arch, err)
+		}
+		if len(candidates) != len(tc.want) {
+			t.Fatalf("%s/%s: expected %d candidates, got %+v", tc.os, tc.arch, len(tc.want), candidates)
+		}
+		for i, c := range candidates {
+			if name := c.ArchiveName("v1"); name != tc.want[i] {
+				t.Fatalf("%s/%s candidate %d: expected %s, got %s", tc.os, tc.arch, i, tc.want[i], name)
+			}
+		}
+		if last := candidates[len(candidates)-1]; last.Emulation != tc.emulated {
+			t.Fatalf("%s/%s: expected emulation %q, got %q", tc.os, tc.arch, tc.emulated, last.Emulation)
+		}
+	}
+}
+
+func TestResolveTargetsBinaryNames(t *testing.T) {
+	windows, err := ResolveTargets("windows", "arm64", "")
+	if err != nil {
+		t.Fatalf("resolve windows: %v", err)
+	}
+	if windows[0].Binary != "aer.exe" || windows[0].OS != "windows" {
+		t.Fatalf("unexpected windows target: %+v", windows[0])
+	}
+
+	linux, err := ResolveTargets("linux", "amd64", LibcMusl)
+	if err != nil {
+		t.Fatalf("resolve linux: %v", err)
+	}
+	if linux[0].Binary != "aer" || linux[0].Libc != LibcMusl {
+		t.Fatalf("unexpected linux target: %+v", linux[0])
+	}
+}
+
+func TestResolveTargetsListsSupportedTargetsOnError(t *testing.T) {
+	_, err := ResolveTargets("linux", "riscv64", "")
+	if err == nil || !strings.Contains(err.Error(), "unsupported architecture") {
+		t.Fatalf("expected unsupported architecture error, got %v", err)
+	}
+
+	_, err = ResolveTargets("freebsd", "amd64", "")
+	if err == nil || !strings.Contains(err.Error(), "unsupported operating system") {
+		t.Fatalf("expected unsupported OS error, got %v", err)
+	}
+
+	saved := targets
+	targets = []Target{{OS: "linux", Arch: "amd64", Libc: LibcGNU, Asset: "linux_amd64", Format: "zip", Binary: "aer"}}
+	defer func() { targets = saved }()
+
+	_, err = ResolveTargets("linux", "arm64", "")
+	if err == nil {
+		t.Fatal("expected error for a host without a published build")
+	}
+	for _, want := range []string{"linux/arm64 (gnu)", "linux/amd64 (gnu)", "windows/arm64 (via Windows x64 emulation)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error should mention %q: %v", want, err)
+		}
+	}
+}
+
+func TestNormalizeLibc(t *testing.T) {
+	for input, want := range map[string]string{"": "", "glibc": LibcGNU, "GNU": LibcGNU, "Alpine": LibcMusl, "musl": LibcMusl} {
+		got, err := NormalizeLibc(input)
+		if err != nil || got != want {
+			t.Fatalf("NormalizeLibc(%q): expected %q, got %q (%v)", input, want, got, err)
+		}
+	}
+	if _, err := NormalizeLibc("uclibc"); err == nil {
+		t.Fatal("expected error for unsupported libc")
+	}
+}
@@ -0,0 +1,75 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"aer/internal/github"
+)
+
+// Source provides the assets of one release.
+type Source interface {
+	// Fetch copies the named asset to dest, returning a *NotFoundError when
+	// the source does not have it.
+	Fetch(name, dest string) error
+	String() string
+}
+
+// ReleaseSource downloads assets from a GitHub release.
+type ReleaseSource struct {
+	Client  *github.Client
+	Repo    string
+	Version string
+}
+
+func (s ReleaseSource) Fetch(name, dest string) error {
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	err = s.Client.DownloadAsset(s.Repo, s.Version, name, out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+		if errors.Is(err, github.ErrNotFound) {
+			return &NotFoundError{Location: s.Client.AssetURL(s.Repo, s.Version, name)}
+		}
+		return err
+	}
+	return nil
+}
+
+func (s ReleaseSource) String() string {
+	return fmt.Sprintf("release %s of %s", s.Version, s.Repo)
+}
+
+// DirSource reads assets pre-staged in a local directory, for runners without
+// access to github.com.
+type DirSource struct {
+	Dir string
+}
+
+func (s DirSource) Fetch(name, dest string) error {
+	src := filepath.Join(s.Dir, name)
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		return &NotFoundError{Location: src}
+	}
+	return CopyFile(src, dest)
+}
+
+func (s DirSource) String() string {
+	return s.Dir
+}
+
+// NotFoundError reports a release asset that does not exist.
+type NotFoundError struct {
+	Location string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found", e.Location)
+}
@@ -0,0 +1,152 @@
+package installer
+
+import (
+	"context"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const verifyTimeout = 30 * time.Second
+
+// versionPattern matches the version template set in main.go.
+var versionPattern = regexp.MustCompile(`(?m)^aer version (\S+)`)
+
+// VerifyBinary runs the installed binary with --version and checks that it
+// reports the expected release. It returns the reported version.
+func VerifyBinary(path, expected string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("run %s --version: %v\n%s%s", path, err, indent(string(output)), diagnoseBinary(path))
+	}
+
+	reported, err := parseVersionOutput(string(output))
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s%s", err, indent(string(output)), diagnoseBinary(path))
+	}
+	if !SameVersion(reported, expected) {
+		return reported, fmt.Errorf("installed binary reports version %s, expected %s\n%s", reported, expected, diagnoseBinary(path))
+	}
+	return reported, nil
+}
+
+func parseVersionOutput(output string) (string, error) {
+	match := versionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("unrecognized --version output")
+	}
+	return match[1], nil
+}
+
+// SameVersion reports whether two version strings name the same release,
+// ignoring a leading "v", so "1.2.3" and "v1.2.3" match.
+func SameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// diagnoseBinary describes the host and the installed file's executable
+// format so architecture mismatches and corrupt extractions are obvious.
+func diagnoseBinary(path string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("  host: %s/%s\n", runtime.GOOS, runtime.GOARCH))
+	sb.WriteString(fmt.Sprintf("  binary: %s\n", describeBinary(path)))
+	return sb.String()
+}
+
+// describeBinary identifies the executable format and architecture of path
+// from its ELF, Mach-O or PE header.
+func describeBinary(path string) string {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		bits := "32-bit"
+		if f.Class == elf.ELFCLASS64 {
+			bits = "64-bit"
+		}
+		return fmt.Sprintf("ELF %s %s", bits, elfArch(f.Machine))
+	}
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		return fmt.Sprintf("Mach-O %s", machoArch(f.Cpu))
+	}
+	if f, err := macho.OpenFat(path); err == nil {
+		defer f.Close()
+		arches := make([]string, 0, len(f.Arches))
+		for _, arch := range f.Arches {
+			arches = append(arches, machoArch(arch.Cpu))
+		}
+		return fmt.Sprintf("Mach-O universal (%s)", strings.Join(arches, ", "))
+	}
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		return fmt.Sprintf("PE %s", peArch(f.FileHeader.Machine))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Sprintf("unreadable (%v)", err)
+	}
+	header := make([]byte, 8)
+	if f, err := os.Open(path); err == nil {
+		n, _ := f.Read(header)
+		header = header[:n]
+		f.Close()
+	}
+	return fmt.Sprintf("unrecognized format, %d bytes, header % x", info.Size(), header)
+}
+
+func elfArch(machine elf.Machine) string {
+	switch machine {
+	case elf.EM_X86_64:
+		return "amd64"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_386:
+		return "386"
+	case elf.EM_ARM:
+		return "arm"
+	default:
+		return machine.String()
+	}
+}
+
+func machoArch(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "amd64"
+	case macho.CpuArm64:
+		return "arm64"
+	default:
+		return cpu.String()
+	}
+}
+
+func peArch(machine uint16) string {
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "amd64"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "386"
+	default:
+		return fmt.Sprintf("machine 0x%04x", machine)
+	}
+}
+
+func indent(s string) string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return ""
+	}
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ") + "\n"
+}
@@ -0,0 +1,80 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeFakeAer(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aer uses a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "aer")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("write fake aer: %v", err)
+	}
+	return path
+}
+
+func TestVerifyBinaryAcceptsMatchingVersion(t *testing.T) {
+	path := writeFakeAer(t, `echo "aer version v0.0.101"`)
+
+	reported, err := VerifyBinary(path, "v0.0.101")
+	if err != nil {
+		t.Fatalf("VerifyBinary: %v", err)
+	}
+	if reported != "v0.0.101" {
+		t.Fatalf("expected reported version v0.0.101, got %s", reported)
+	}
+}
+
+func TestVerifyBinaryRejectsMismatchedVersion(t *testing.T) {
+	path := writeFakeAer(t, `echo "aer version v0.0.100"`)
+
+	_, err := VerifyBinary(path, "v0.0.101")
+	if err == nil || !strings.Contains(err.Error(), "reports version v0.0.100, expected v0.0.101") {
+		t.Fatalf("expected version mismatch error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "host: "+runtime.GOOS+"/"+runtime.GOARCH) {
+		t.Fatalf("error should include host diagnostics: %v", err)
+	}
+}
+
+func TestVerifyBinaryDiagnosesUnrunnableBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on exec format errors")
+	}
+	path := filepath.Join(t.TempDir(), "aer")
+	if err := os.WriteFile(path, []byte("PK\x03\x04garbage"), 0o755); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_, err := VerifyBinary(path, "v0.0.101")
+	if err == nil {
+		t.Fatal("expected error for a corrupt binary")
+	}
+	if !strings.Contains(err.Error(), "unrecognized format") || !strings.Contains(err.Error(), "50 4b 03 04") {
+		t.Fatalf("error should describe the file header: %v", err)
+	}
+}
+
+func TestParseVersionOutput(t *testing.T) {
+	version, err := parseVersionOutput("aer version v1.2.3\ncommit: abc\n")
+	if err != nil || version != "v1.2.3" {
+		t.Fatalf("unexpected parse result %q (%v)", version, err)
+	}
+	if _, err := parseVersionOutput("Usage: aer [command]"); err == nil {
+		t.Fatal("expected error for unrecognized output")
+	}
+}
+
+func TestDescribeBinaryIdentifiesExecutables(t *testing.T) {
+	description := describeBinary(os.Args[0])
+	if !strings.Contains(description, runtime.GOARCH) {
+		t.Fatalf("expected the test binary to be described as %s, got %q", runtime.GOARCH, description)
+	}
+}
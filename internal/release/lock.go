@@ -0,0 +1,80 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// LockFileName is the conventional name of the lock file.
+const LockFileName = "aer.lock"
+
+// Lock records the release a project resolved to and the digest of every
+// platform's asset, so reruns and audits install exactly the same binary.
+type Lock struct {
+	Version    string      `json:"version"`
+	Repository string      `json:"repository"`
+	Assets     []LockAsset `json:"assets"`
+}
+
+// LockAsset is one platform's release archive.
+type LockAsset struct {
+	Platform string `json:"platform"`
+	Name     string `json:"name"`
+	SHA256   string `json:"sha256"`
+}
+
+// NewLock builds a lock for version from the release checksums, keeping only
+// the platform archives.
+func NewLock(repository, version string, sums Checksums) (Lock, error) {
+	lock := Lock{Version: version, Repository: repository}
+	for name, digest := range sums {
+		platform, assetVersion, ok := ParseArchiveName(name)
+		if !ok || assetVersion != version {
+			continue
+		}
+		lock.Assets = append(lock.Assets, LockAsset{Platform: platform, Name: name, SHA256: digest})
+	}
+	if len(lock.Assets) == 0 {
+		return Lock{}, fmt.Errorf("release checksums list no archives for %s", version)
+	}
+	sort.Slice(lock.Assets, func(i, j int) bool {
+		return lock.Assets[i].Platform < lock.Assets[j].Platform
+	})
+	return lock, nil
+}
+
+// ReadLock loads a lock file.
+func ReadLock(path string) (Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Lock{}, err
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return Lock{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if lock.Version == "" {
+		return Lock{}, fmt.Errorf("%s does not record a version", path)
+	}
+	return lock, nil
+}
+
+// Write saves the lock file with stable formatting so it diffs cleanly.
+func (l Lock) Write(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Checksums returns the locked digests in the form used to verify downloads.
+func (l Lock) Checksums() Checksums {
+	sums := make(Checksums, len(l.Assets))
+	for _, asset := range l.Assets {
+		sums[asset.Name] = asset.SHA256
+	}
+	return sums
+}
@@ -0,0 +1,47 @@
+package release
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLockRoundTrip(t *testing.T) {
+	linux := strings.Repeat("a", 64)
+	darwin := strings.Repeat("b", 64)
+	sums := Checksums{
+		"aer_linux_amd64_v1.0.0.zip":  linux,
+		"aer_darwin_arm64_v1.0.0.zip": darwin,
+		"SHA256SUMS-v1.0.0":           strings.Repeat("c", 64),
+		"aer_linux_amd64_v0.9.0.zip":  strings.Repeat("d", 64),
+	}
+
+	lock, err := NewLock("octoberswimmer/aer-dist", "v1.0.0", sums)
+	if err != nil {
+		t.Fatalf("NewLock: %v", err)
+	}
+	if len(lock.Assets) != 2 || lock.Assets[0].Platform != "darwin_arm64" || lock.Assets[1].SHA256 != linux {
+		t.Fatalf("unexpected lock assets: %+v", lock.Assets)
+	}
+
+	path := filepath.Join(t.TempDir(), LockFileName)
+	if err := lock.Write(path); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	read, err := ReadLock(path)
+	if err != nil {
+		t.Fatalf("ReadLock: %v", err)
+	}
+	if read.Version != "v1.0.0" || read.Repository != "octoberswimmer/aer-dist" {
+		t.Fatalf("unexpected lock: %+v", read)
+	}
+	if read.Checksums()["aer_darwin_arm64_v1.0.0.zip"] != darwin {
+		t.Fatalf("locked checksums missing darwin digest: %v", read.Checksums())
+	}
+}
+
+func TestNewLockRequiresArchives(t *testing.T) {
+	if _, err := NewLock("repo", "v1.0.0", Checksums{"SHA256SUMS-v1.0.0": strings.Repeat("c", 64)}); err == nil {
+		t.Fatal("expected error when no archives are listed")
+	}
+}
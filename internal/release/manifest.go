@@ -0,0 +1,217 @@
+package release
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"aer/internal/sbom"
+)
+
+// ManifestName returns the name of the machine-readable manifest published
+// with a release.
+func ManifestName(version string) string {
+	return "manifest-" + version + ".json"
+}
+
+// Manifest describes the archives published for a release: which platform
+// each one targets, the binary inside it, the oldest OS release it runs on,
+// its size and digest, and the SBOMs published for it.
+type Manifest struct {
+	Version string          `json:"version"`
+	Assets  []ManifestAsset `json:"assets"`
+}
+
+// ManifestAsset is one platform's release archive.
+type ManifestAsset struct {
+	Name     string `json:"name"`
+	Platform string `json:"platform"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Binary   string `json:"binary"`
+	MinOS    string `json:"min_os,omitempty"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	SBOMs    []SBOM `json:"sboms,omitempty"`
+}
+
+// SBOM is a software bill of materials published for an archive's binary.
+type SBOM struct {
+	Format string `json:"format"`
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// minimumOS is the oldest release of each OS that binaries built with the Go
+// version in go.mod run on: macOS and Windows versions, and the Linux kernel
+// version. Update it when moving to a Go release that drops support.
+var minimumOS = map[string]string{
+	"darwin":  "12",
+	"linux":   "3.2",
+	"windows": "10",
+}
+
+// BuildManifest describes the archives for version found in dir, with the
+// SBOMs staged next to them. Files that are not release archives, or belong
+// to another version, are ignored.
+func BuildManifest(dir, version string) (Manifest, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Manifest{}, err
+	}
+	manifest := Manifest{Version: version}
+	for _, entry := range entries {
+		platform, assetVersion, ok := ParseArchiveName(entry.Name())
+		if !ok || assetVersion != version || !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return Manifest{}, err
+		}
+		digest, err := FileSHA256(path)
+		if err != nil {
+			return Manifest{}, err
+		}
+		goos, arch, _ := strings.Cut(platform, "_")
+		binary := "aer"
+		if goos == "windows" {
+			binary = "aer.exe"
+		}
+		sboms, err := findSBOMs(dir, platform, version)
+		if err != nil {
+			return Manifest{}, err
+		}
+		manifest.Assets = append(manifest.Assets, ManifestAsset{
+			Name:     entry.Name(),
+			Platform: platform,
+			OS:       goos,
+			Arch:     arch,
+			Binary:   binary,
+			MinOS:    minimumOS[goos],
+			Size:     info.Size(),
+			SHA256:   digest,
+			SBOMs:    sboms,
+		})
+	}
+	if len(manifest.Assets) == 0 {
+		return Manifest{}, fmt.Errorf("no aer %s archives found in %s", version, dir)
+	}
+	sort.Slice(manifest.Assets, func(i, j int) bool {
+		return manifest.Assets[i].Platform < manifest.Assets[j].Platform
+	})
+	return manifest, nil
+}
+
+// findSBOMs lists the SBOMs in dir for a platform's archive.
+func findSBOMs(dir, platform, version string) ([]SBOM, error) {
+	var sboms []SBOM
+	for _, format := range sbom.Formats {
+		ext, _ := sbom.Extension(format)
+		name := SBOMName(platform, version, ext)
+		digest, err := FileSHA256(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		sboms = append(sboms, SBOM{Format: format, Name: name, SHA256: digest})
+	}
+	return sboms, nil
+}
+
+// SBOM returns the asset's SBOM in format, if one is published.
+func (a ManifestAsset) SBOM(format string) (SBOM, bool) {
+	for _, s := range a.SBOMs {
+		if s.Format == format {
+			return s, true
+		}
+	}
+	return SBOM{}, false
+}
+
+// ParseManifest decodes a release manifest. The file names it lists are
+// used as paths when installing, so each must be a plain file name, and each
+// archive must be named for its platform and the manifest's version.
+func ParseManifest(data []byte) (Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("parse release manifest: %w", err)
+	}
+	if manifest.Version == "" {
+		return Manifest{}, fmt.Errorf("release manifest does not record a version")
+	}
+	for _, asset := range manifest.Assets {
+		if err := checkFileName("archive", asset.Name); err != nil {
+			return Manifest{}, err
+		}
+		platform, version, ok := ParseArchiveName(asset.Name)
+		if !ok || platform != asset.Platform || version != manifest.Version {
+			return Manifest{}, fmt.Errorf("release manifest lists %s as the %s archive for %s", asset.Name, asset.Platform, manifest.Version)
+		}
+		if asset.Binary != "" {
+			if err := checkFileName("binary", asset.Binary); err != nil {
+				return Manifest{}, err
+			}
+		}
+		for _, s := range asset.SBOMs {
+			if err := checkFileName("SBOM", s.Name); err != nil {
+				return Manifest{}, err
+			}
+		}
+	}
+	return manifest, nil
+}
+
+// checkFileName rejects names that are not a single path element on every
+// platform.
+func checkFileName(kind, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:`) || filepath.Base(name) != name {
+		return fmt.Errorf("release manifest lists %s name %q, which is not a plain file name", kind, name)
+	}
+	return nil
+}
+
+// JSON encodes the manifest with stable formatting.
+func (m Manifest) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Checksums returns the archive and SBOM digests in the form used to verify
+// downloads.
+func (m Manifest) Checksums() Checksums {
+	sums := make(Checksums, len(m.Assets))
+	for _, asset := range m.Assets {
+		sums[asset.Name] = asset.SHA256
+		for _, s := range asset.SBOMs {
+			sums[s.Name] = s.SHA256
+		}
+	}
+	return sums
+}
+
+// SHA256Sums renders the archive and SBOM digests in `shasum -a 256` format, sorted by
+// name, as published in SHA256SUMS-<version>.
+func (m Manifest) SHA256Sums() []byte {
+	names := make([]string, 0, len(m.Assets))
+	sums := m.Checksums()
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s  %s\n", sums[name], name)
+	}
+	return buf.Bytes()
+}
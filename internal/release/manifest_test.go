@@ -0,0 +1,73 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildManifest(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"aer_linux_amd64_v1.0.0.zip":      "hello\n",
+		"aer_windows_amd64_v1.0.0.zip":    "windows",
+		"aer_linux_amd64_v0.9.0.zip":      "old",
+		"aer_linux_amd64_v1.0.0.cdx.json": "{}\n",
+		"SHA256SUMS-v1.0.0":               "ignored",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	manifest, err := BuildManifest(dir, "v1.0.0")
+	if err != nil {
+		t.Fatalf("BuildManifest: %v", err)
+	}
+	if len(manifest.Assets) != 2 {
+		t.Fatalf("expected two assets, got %+v", manifest.Assets)
+	}
+	linux, windows := manifest.Assets[0], manifest.Assets[1]
+	if linux.Platform != "linux_amd64" || linux.OS != "linux" || linux.Arch != "amd64" || linux.Binary != "aer" || linux.MinOS != "3.2" || linux.Size != 6 {
+		t.Fatalf("unexpected linux asset: %+v", linux)
+	}
+	if linux.SHA256 != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		t.Fatalf("unexpected linux digest %s", linux.SHA256)
+	}
+	if windows.Binary != "aer.exe" {
+		t.Fatalf("unexpected windows binary %q", windows.Binary)
+	}
+	if sbom, ok := linux.SBOM("cyclonedx"); !ok || sbom.Name != "aer_linux_amd64_v1.0.0.cdx.json" || len(linux.SBOMs) != 1 {
+		t.Fatalf("unexpected linux SBOMs: %+v", linux.SBOMs)
+	}
+	if len(windows.SBOMs) != 0 {
+		t.Fatalf("unexpected windows SBOMs: %+v", windows.SBOMs)
+	}
+
+	sums, err := ParseChecksums(manifest.SHA256Sums())
+	if err != nil {
+		t.Fatalf("ParseChecksums: %v", err)
+	}
+	if len(sums) != 3 || sums["aer_linux_amd64_v1.0.0.zip"] != linux.SHA256 || sums["aer_linux_amd64_v1.0.0.cdx.json"] != linux.SBOMs[0].SHA256 {
+		t.Fatalf("unexpected checksums: %v", sums)
+	}
+
+	data, err := manifest.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	parsed, err := ParseManifest(data)
+	if err != nil {
+		t.Fatalf("ParseManifest: %v", err)
+	}
+	if parsed.Version != "v1.0.0" || len(parsed.Assets) != 2 || !reflect.DeepEqual(parsed.Assets, manifest.Assets) {
+		t.Fatalf("manifest did not round-trip: %+v", parsed)
+	}
+}
+
+func TestBuildManifestRequiresArchives(t *testing.T) {
+	if _, err := BuildManifest(t.TempDir(), "v1.0.0"); err == nil {
+		t.Fatal("expected error for a directory without archives")
+	}
+}
@@ -0,0 +1,168 @@
+// Package release describes the assets published for each aer release and
+// verifies downloaded archives against the release checksums.
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// archivePattern matches names such as aer_linux_amd64_v1.2.3.zip.
+var archivePattern = regexp.MustCompile(`^aer_([a-z0-9]+)_([a-z0-9]+)_(.+)\.zip$`)
+
+// ChecksumsName returns the name of the SHA-256 manifest published with a
+// release, as produced by `make checksum`.
+func ChecksumsName(version string) string {
+	return "SHA256SUMS-" + version
+}
+
+// SBOMName returns the name of the SBOM published for a platform's archive,
+// such as aer_linux_amd64_v1.2.3.cdx.json. ext is the format's extension from
+// sbom.Extension.
+func SBOMName(platform, version, ext string) string {
+	return "aer_" + platform + "_" + version + ext
+}
+
+// ParseArchiveName splits a release archive name into its platform
+// ("linux_amd64") and version. It reports false for names that do not follow
+// the release naming scheme.
+func ParseArchiveName(name string) (platform, version string, ok bool) {
+	match := archivePattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1] + "_" + match[2], match[3], true
+}
+
+// VersionFromChecksumsName extracts the version from a SHA256SUMS-<version>
+// file name.
+func VersionFromChecksumsName(name string) (string, bool) {
+	version, ok := strings.CutPrefix(name, "SHA256SUMS-")
+	if !ok || version == "" {
+		return "", false
+	}
+	return version, true
+}
+
+// VersionFromManifestName extracts the version from a
+// manifest-<version>.json file name.
+func VersionFromManifestName(name string) (string, bool) {
+	version, ok := strings.CutPrefix(name, "manifest-")
+	if !ok {
+		return "", false
+	}
+	version, ok = strings.CutSuffix(version, ".json")
+	if !ok || version == "" {
+		return "", false
+	}
+	return version, true
+}
+
+// Checksums maps asset names to lower-case hex SHA-256 digests.
+type Checksums map[string]string
+
+// ParseChecksums reads a `shasum -a 256` manifest.
+func ParseChecksums(data []byte) (Checksums, error) {
+	sums := make(Checksums)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("checksums line %d: expected '<digest>  <file>'", line)
+		}
+		digest := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+			return nil, fmt.Errorf("checksums line %d: invalid SHA-256 digest %q", line, fields[0])
+		}
+		// shasum marks binary-mode entries with a leading '*'.
+		sums[strings.TrimPrefix(fields[1], "*")] = digest
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// Verify checks the file at path against the digest recorded for name.
+func (c Checksums) Verify(path, name string) error {
+	want, ok := c[name]
+	if !ok {
+		return fmt.Errorf("%s is not listed in the release checksums", name)
+	}
+	got, err := FileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, want, got)
+	}
+	return nil
+}
+
+// FileSHA256 returns the lower-case hex SHA-256 digest of a file.
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VersionFromDir infers the release version of the assets staged in dir from
+// the manifest-<version>.json or SHA256SUMS-<version> files, or from the
+// archive names when there are neither.
+func VersionFromDir(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	versions := make(map[string]bool)
+	for _, entry := range entries {
+		if version, ok := VersionFromManifestName(entry.Name()); ok {
+			versions[version] = true
+		}
+		if version, ok := VersionFromChecksumsName(entry.Name()); ok {
+			versions[version] = true
+		}
+	}
+	if len(versions) == 0 {
+		for _, entry := range entries {
+			if _, version, ok := ParseArchiveName(entry.Name()); ok {
+				versions[version] = true
+			}
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return "", fmt.Errorf("no aer release assets found in %s", dir)
+	case 1:
+		for version := range versions {
+			return version, nil
+		}
+	}
+	found := make([]string, 0, len(versions))
+	for version := range versions {
+		found = append(found, version)
+	}
+	sort.Strings(found)
+	return "", fmt.Errorf("%s contains assets for several versions (%v); pass --version", dir, found)
+}
@@ -0,0 +1,104 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseArchiveName(t *testing.T) {
+	platform, version, ok := ParseArchiveName("aer_darwin_arm64_v0.0.101.zip")
+	if !ok || platform != "darwin_arm64" || version != "v0.0.101" {
+		t.Fatalf("unexpected parse: %q %q %v", platform, version, ok)
+	}
+	for _, name := range []string{"aer.zip", "aer_linux_amd64.zip", "other_linux_amd64_v1.zip", "aer_linux_amd64_v1.tar.gz"} {
+		if _, _, ok := ParseArchiveName(name); ok {
+			t.Fatalf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestVersionFromChecksumsName(t *testing.T) {
+	if version, ok := VersionFromChecksumsName("SHA256SUMS-v1.2.3"); !ok || version != "v1.2.3" {
+		t.Fatalf("unexpected version %q", version)
+	}
+	if _, ok := VersionFromChecksumsName("SHA256SUMS-"); ok {
+		t.Fatal("expected empty version to be rejected")
+	}
+}
+
+func TestParseChecksumsAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "aer_linux_amd64_v1.zip")
+	if err := os.WriteFile(archive, []byte("hello\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// sha256("hello\n")
+	const digest = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+	sums, err := ParseChecksums([]byte(strings.ToUpper(digest) + "  aer_linux_amd64_v1.zip\n" +
+		strings.Repeat("0", 64) + " *aer_windows_amd64_v1.zip\n\n"))
+	if err != nil {
+		t.Fatalf("ParseChecksums: %v", err)
+	}
+	if sums["aer_windows_amd64_v1.zip"] == "" {
+		t.Fatalf("binary-mode entry not parsed: %v", sums)
+	}
+	if err := sums.Verify(archive, "aer_linux_amd64_v1.zip"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := sums.Verify(archive, "aer_windows_amd64_v1.zip"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if err := sums.Verify(archive, "aer_darwin_amd64_v1.zip"); err == nil || !strings.Contains(err.Error(), "not listed") {
+		t.Fatalf("expected unlisted asset error, got %v", err)
+	}
+}
+
+func TestParseChecksumsRejectsMalformedLines(t *testing.T) {
+	for _, data := range []string{"abc  file.zip", "only-one-field", strings.Repeat("z", 64) + "  file.zip"} {
+		if _, err := ParseChecksums([]byte(data)); err == nil {
+			t.Fatalf("expected error for %q", data)
+		}
+	}
+}
+
+func TestVersionFromDir(t *testing.T) {
+	touch := func(dir, name string) {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	withSums := t.TempDir()
+	touch(withSums, "SHA256SUMS-v1.2.3")
+	touch(withSums, "aer_linux_amd64_v1.2.3.zip")
+	if version, err := VersionFromDir(withSums); err != nil || version != "v1.2.3" {
+		t.Fatalf("expected v1.2.3 from checksums, got %q (%v)", version, err)
+	}
+
+	withManifest := t.TempDir()
+	touch(withManifest, "manifest-v1.3.0.json")
+	if version, err := VersionFromDir(withManifest); err != nil || version != "v1.3.0" {
+		t.Fatalf("expected v1.3.0 from the manifest, got %q (%v)", version, err)
+	}
+
+	archivesOnly := t.TempDir()
+	touch(archivesOnly, "aer_linux_amd64_v2.0.0.zip")
+	touch(archivesOnly, "aer_darwin_arm64_v2.0.0.zip")
+	if version, err := VersionFromDir(archivesOnly); err != nil || version != "v2.0.0" {
+		t.Fatalf("expected v2.0.0 from archive names, got %q (%v)", version, err)
+	}
+
+	mixed := t.TempDir()
+	touch(mixed, "aer_linux_amd64_v1.0.0.zip")
+	touch(mixed, "aer_linux_amd64_v1.1.0.zip")
+	if _, err := VersionFromDir(mixed); err == nil || !strings.Contains(err.Error(), "several versions") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+
+	if _, err := VersionFromDir(t.TempDir()); err == nil {
+		t.Fatal("expected error for an empty directory")
+	}
+}
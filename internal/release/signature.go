@@ -0,0 +1,203 @@
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// PublicKey verifies detached signatures over a checksum manifest.
+type PublicKey interface {
+	// SignatureName returns the name of the signature asset published
+	// alongside the manifest for version.
+	SignatureName(version string) string
+	// Verify checks signature, the contents of the signature asset, against
+	// message.
+	Verify(message, signature []byte) error
+	String() string
+}
+
+// releaseKey is the minisign public key the project signs its releases'
+// checksums with.
+//
+//go:embed aer-release.pub
+var releaseKey []byte
+
+// ReleaseKey returns the pinned key releases are signed with. Installs and
+// self-updates check signatures against it unless another key is given.
+func ReleaseKey() PublicKey {
+	key, err := ParsePublicKey(releaseKey)
+	if err != nil {
+		panic(fmt.Sprintf("parse embedded release key: %v", err))
+	}
+	return key
+}
+
+// ParsePublicKey reads a minisign public key (with or without its untrusted
+// comment line) or a PEM-encoded ECDSA P-256 or Ed25519 key as written by
+// `cosign generate-key-pair`.
+func ParsePublicKey(data []byte) (PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		return parseCosignKey(block)
+	}
+	return parseMinisignKey(data)
+}
+
+// minisignKey is a minisign Ed25519 public key.
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+func parseMinisignKey(data []byte) (*minisignKey, error) {
+	lines := nonCommentLines(data)
+	if len(lines) != 1 {
+		return nil, errors.New("public key is neither PEM nor a minisign key")
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return nil, fmt.Errorf("decode minisign public key: %w", err)
+	}
+	if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, errors.New("unsupported minisign public key")
+	}
+	k := &minisignKey{key: ed25519.PublicKey(raw[10:])}
+	copy(k.id[:], raw[2:10])
+	return k, nil
+}
+
+func (k *minisignKey) SignatureName(version string) string {
+	return ChecksumsName(version) + ".minisig"
+}
+
+// Verify checks a .minisig file: the signature over the message (hashed with
+// BLAKE2b-512 for "ED" signatures) and the global signature over the trusted
+// comment.
+func (k *minisignKey) Verify(message, signature []byte) error {
+	lines := strings.Split(strings.TrimRight(string(signature), "\r\n"), "\n")
+	if len(lines) != 4 {
+		return errors.New("malformed minisign signature: expected 4 lines")
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return errors.New("malformed minisign signature")
+	}
+	if !bytes.Equal(sig[2:10], k.id[:]) {
+		return fmt.Errorf("signature was made with key %016X, expected %016X", binary.LittleEndian.Uint64(sig[2:10]), binary.LittleEndian.Uint64(k.id[:]))
+	}
+
+	signed := message
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		digest := blake2b.Sum512(message)
+		signed = digest[:]
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm %q", sig[:2])
+	}
+	if !ed25519.Verify(k.key, signed, sig[10:]) {
+		return errors.New("minisign signature does not match")
+	}
+
+	comment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return errors.New("malformed minisign signature: missing trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return errors.New("malformed minisign global signature")
+	}
+	trusted := append(append([]byte{}, sig[10:]...), comment...)
+	if !ed25519.Verify(k.key, trusted, global) {
+		return errors.New("minisign trusted comment signature does not match")
+	}
+	return nil
+}
+
+func (k *minisignKey) String() string {
+	return fmt.Sprintf("minisign key %016X", binary.LittleEndian.Uint64(k.id[:]))
+}
+
+// cosignKey is a public key for signatures made with `cosign sign-blob`.
+type cosignKey struct {
+	key any
+}
+
+func parseCosignKey(block *pem.Block) (*cosignKey, error) {
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	switch k := key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return &cosignKey{key: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func (k *cosignKey) SignatureName(version string) string {
+	return ChecksumsName(version) + ".sig"
+}
+
+// Verify checks a base64-encoded blob signature: ASN.1 ECDSA over the SHA-256
+// digest of message, or Ed25519 over message itself.
+func (k *cosignKey) Verify(message, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errors.New("signature does not match")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, sig) {
+			return errors.New("signature does not match")
+		}
+	}
+	return nil
+}
+
+func (k *cosignKey) String() string {
+	if _, ok := k.key.(*ecdsa.PublicKey); ok {
+		return "cosign ECDSA key"
+	}
+	return "cosign Ed25519 key"
+}
+
+// nonCommentLines returns the trimmed, non-empty lines of data other than
+// minisign's "untrusted comment:" header.
+func nonCommentLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,32 @@
+package release
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("v0.0.101")
+	if err != nil || v != (Version{Patch: 101}) || v.String() != "v0.0.101" {
+		t.Fatalf("unexpected parse %+v (%v)", v, err)
+	}
+	v, err = ParseVersion("1.2.3-rc.1")
+	if err != nil || v.String() != "v1.2.3-rc.1" {
+		t.Fatalf("unexpected parse %+v (%v)", v, err)
+	}
+	for _, tag := range []string{"latest", "v1.2", "v1.02.3", "v1.2.x"} {
+		if _, err := ParseVersion(tag); err == nil {
+			t.Fatalf("expected %q to be rejected", tag)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tags := []string{"v1.0.0", "dev", "v0.0.101", "v1.0.0-rc.10", "v0.0.99", "v1.0.0-rc.2", "v1.0.0-beta"}
+	sort.Slice(tags, func(i, j int) bool { return CompareVersions(tags[i], tags[j]) < 0 })
+	want := "dev v0.0.99 v0.0.101 v1.0.0-beta v1.0.0-rc.2 v1.0.0-rc.10 v1.0.0"
+	if got := strings.Join(tags, " "); got != want {
+		t.Fatalf("unexpected order:\n got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package sbom
+
+import "time"
+
+// CycloneDX 1.5 JSON documents. Only the fields aer-dist fills in are
+// modelled.
+type cdxDocument struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Hashes     []cdxHash     `json:"hashes,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// cycloneDX builds the CycloneDX document for b. Build information does not
+// record the module graph, so every module is listed as a direct dependency
+// of the application.
+func cycloneDX(b Binary) cdxDocument {
+	app := cdxComponent{
+		Type:    "application",
+		BOMRef:  b.purl(),
+		Name:    b.Name,
+		Version: b.Version,
+		PURL:    b.purl(),
+	}
+	if b.SHA256 != "" {
+		app.Hashes = []cdxHash{{Alg: "SHA-256", Content: b.SHA256}}
+	}
+	if b.Info.GoVersion != "" {
+		app.Properties = append(app.Properties, cdxProperty{Name: "golang:goversion", Value: b.Info.GoVersion})
+	}
+	for _, key := range buildProperties {
+		if value := b.setting(key); value != "" {
+			app.Properties = append(app.Properties, cdxProperty{Name: "golang:build:" + key, Value: value})
+		}
+	}
+
+	doc := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + b.uuid(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: b.created().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: "aer-dist release"}}},
+			Component: app,
+		},
+		Components: []cdxComponent{},
+	}
+	root := cdxDependency{Ref: app.BOMRef}
+	for _, m := range b.modules() {
+		c := cdxComponent{
+			Type:    "library",
+			BOMRef:  m.purl(),
+			Name:    m.Path,
+			Version: m.Version,
+			PURL:    m.purl(),
+		}
+		if m.Sum != "" {
+			c.Properties = []cdxProperty{{Name: "golang:sum", Value: m.Sum}}
+		}
+		doc.Components = append(doc.Components, c)
+		doc.Dependencies = append(doc.Dependencies, cdxDependency{Ref: c.BOMRef})
+		root.DependsOn = append(root.DependsOn, c.BOMRef)
+	}
+	doc.Dependencies = append([]cdxDependency{root}, doc.Dependencies...)
+	return doc
+}
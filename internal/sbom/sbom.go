@@ -0,0 +1,154 @@
+// Package sbom renders software bills of materials for a Go binary in
+// CycloneDX and SPDX JSON from the module information the linker embeds in
+// it, so the documents list exactly the modules that were linked.
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Supported formats.
+const (
+	CycloneDX = "cyclonedx"
+	SPDX      = "spdx"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{CycloneDX, SPDX}
+
+// Extension returns the file name suffix used for documents in format.
+func Extension(format string) (string, error) {
+	switch format {
+	case CycloneDX:
+		return ".cdx.json", nil
+	case SPDX:
+		return ".spdx.json", nil
+	}
+	return "", fmt.Errorf("unknown SBOM format %q (want %s or %s)", format, CycloneDX, SPDX)
+}
+
+// Binary describes the binary an SBOM is generated for.
+type Binary struct {
+	// Name and Version identify the application, e.g. "aer" and "v1.2.3".
+	Name    string
+	Version string
+	// PURL is the package URL of the application. It defaults to a golang
+	// package URL for the main module.
+	PURL string
+	// Platform is the target the binary was built for, such as linux_amd64.
+	Platform string
+	// SHA256 is the hex digest of the binary, when known.
+	SHA256 string
+	// Info is the binary's embedded build information.
+	Info *debug.BuildInfo
+	// Created is the document timestamp. It defaults to the commit time
+	// stamped in the build information, so regenerating the SBOM for the same
+	// binary gives the same document.
+	Created time.Time
+}
+
+// Encode renders the SBOM for b in format.
+func Encode(format string, b Binary) ([]byte, error) {
+	var doc any
+	switch format {
+	case CycloneDX:
+		doc = cycloneDX(b)
+	case SPDX:
+		doc = spdx(b)
+	default:
+		_, err := Extension(format)
+		return nil, err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// module is a linked module as it appears in the SBOM.
+type module struct {
+	Path    string
+	Version string
+	// Sum is the go.sum hash ("h1:...") of the module.
+	Sum string
+}
+
+func (m module) purl() string {
+	return "pkg:golang/" + m.Path + "@" + m.Version
+}
+
+// modules returns the linked dependencies, resolving replacements to the
+// module that was actually built, followed by the Go standard library.
+func (b Binary) modules() []module {
+	var mods []module
+	for _, dep := range b.Info.Deps {
+		m := dep
+		if dep.Replace != nil {
+			m = dep.Replace
+		}
+		version := m.Version
+		if version == "" {
+			version = "(devel)"
+		}
+		mods = append(mods, module{Path: m.Path, Version: version, Sum: m.Sum})
+	}
+	if b.Info.GoVersion != "" {
+		goVersion, _, _ := strings.Cut(b.Info.GoVersion, " ")
+		mods = append(mods, module{Path: "stdlib", Version: goVersion})
+	}
+	return mods
+}
+
+func (b Binary) purl() string {
+	if b.PURL != "" {
+		return b.PURL
+	}
+	return module{Path: b.Info.Main.Path, Version: b.Version}.purl()
+}
+
+func (b Binary) created() time.Time {
+	if !b.Created.IsZero() {
+		return b.Created.UTC()
+	}
+	if t, err := time.Parse(time.RFC3339, b.setting("vcs.time")); err == nil {
+		return t.UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (b Binary) setting(key string) string {
+	for _, s := range b.Info.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// uuid derives a stable version 5 style UUID from the binary's identity and
+// build information, so the same binary always gets the same serial number.
+func (b Binary) uuid() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s", b.Name, b.Version, b.Platform, b.SHA256, b.Info.String())
+	sum := h.Sum(nil)
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// buildProperties are the build settings recorded as properties of the
+// application.
+var buildProperties = []string{"GOOS", "GOARCH", "CGO_ENABLED", "vcs.revision", "vcs.time", "vcs.modified"}
+
+var spdxIDInvalid = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+func spdxID(parts ...string) string {
+	return "SPDXRef-" + strings.Trim(spdxIDInvalid.ReplaceAllString(strings.Join(parts, "-"), "-"), "-")
+}
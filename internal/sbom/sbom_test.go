@@ -0,0 +1,113 @@
+package sbom
+
+import (
+	"encoding/json"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func testBinary() Binary {
+	return Binary{
+		Name:     "aer",
+		Version:  "v1.2.3",
+		PURL:     "pkg:github/octoberswimmer/aer-dist@v1.2.3",
+		Platform: "linux_amd64",
+		SHA256:   strings.Repeat("ab", 32),
+		Info: &debug.BuildInfo{
+			GoVersion: "go1.25.3",
+			Path:      "aer",
+			Main:      debug.Module{Path: "aer", Version: "v1.2.3"},
+			Deps: []*debug.Module{
+				{Path: "github.com/octoberswimmer/aer", Version: "v0.0.101", Sum: "h1:abc="},
+				{Path: "modernc.org/sqlite", Version: "v1.0.0", Replace: &debug.Module{Path: "example.com/sqlite", Version: "v1.0.1", Sum: "h1:def="}},
+			},
+			Settings: []debug.BuildSetting{
+				{Key: "GOOS", Value: "linux"},
+				{Key: "GOARCH", Value: "amd64"},
+				{Key: "vcs.time", Value: "2025-01-02T03:04:05Z"},
+			},
+		},
+	}
+}
+
+func TestCycloneDX(t *testing.T) {
+	data, err := Encode(CycloneDX, testBinary())
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var doc cdxDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.BOMFormat != "CycloneDX" || doc.SpecVersion != "1.5" || !strings.HasPrefix(doc.SerialNumber, "urn:uuid:") {
+		t.Fatalf("unexpected header: %+v", doc)
+	}
+	if doc.Metadata.Timestamp != "2025-01-02T03:04:05Z" {
+		t.Fatalf("timestamp should come from vcs.time, got %s", doc.Metadata.Timestamp)
+	}
+	app := doc.Metadata.Component
+	if app.PURL != "pkg:github/octoberswimmer/aer-dist@v1.2.3" || len(app.Hashes) != 1 || app.Hashes[0].Content != strings.Repeat("ab", 32) {
+		t.Fatalf("unexpected application component: %+v", app)
+	}
+
+	var purls []string
+	for _, c := range doc.Components {
+		purls = append(purls, c.PURL)
+	}
+	want := []string{
+		"pkg:golang/github.com/octoberswimmer/aer@v0.0.101",
+		"pkg:golang/example.com/sqlite@v1.0.1",
+		"pkg:golang/stdlib@go1.25.3",
+	}
+	if strings.Join(purls, " ") != strings.Join(want, " ") {
+		t.Fatalf("components = %v, want %v", purls, want)
+	}
+	if doc.Components[1].Properties[0].Value != "h1:def=" {
+		t.Fatalf("replacement should carry its own go.sum hash: %+v", doc.Components[1])
+	}
+	if len(doc.Dependencies) != 4 || doc.Dependencies[0].Ref != app.BOMRef || len(doc.Dependencies[0].DependsOn) != 3 {
+		t.Fatalf("unexpected dependencies: %+v", doc.Dependencies)
+	}
+}
+
+func TestSPDX(t *testing.T) {
+	data, err := Encode(SPDX, testBinary())
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var doc spdxDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.Name != "aer-v1.2.3-linux_amd64" || !strings.HasPrefix(doc.DocumentNamespace, NamespacePrefix+"aer-v1.2.3-linux_amd64-") {
+		t.Fatalf("unexpected header: %+v", doc)
+	}
+	if len(doc.Packages) != 4 || len(doc.Relationships) != 4 {
+		t.Fatalf("expected the application and three modules, got %d packages and %d relationships", len(doc.Packages), len(doc.Relationships))
+	}
+	for _, pkg := range doc.Packages {
+		if strings.ContainsAny(strings.TrimPrefix(pkg.SPDXID, "SPDXRef-"), "/@_ ") {
+			t.Fatalf("invalid SPDX identifier %q", pkg.SPDXID)
+		}
+	}
+	if doc.Packages[0].Checksums[0].ChecksumValue != strings.Repeat("ab", 32) {
+		t.Fatalf("application checksum missing: %+v", doc.Packages[0])
+	}
+}
+
+func TestEncodeIsDeterministic(t *testing.T) {
+	for _, format := range Formats {
+		a, err := Encode(format, testBinary())
+		if err != nil {
+			t.Fatalf("Encode %s: %v", format, err)
+		}
+		b, _ := Encode(format, testBinary())
+		if string(a) != string(b) {
+			t.Fatalf("%s output differs between runs", format)
+		}
+	}
+	if _, err := Encode("swid", testBinary()); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,121 @@
+package sbom
+
+import "time"
+
+// NamespacePrefix prefixes the documentNamespace of SPDX documents.
+const NamespacePrefix = "https://github.com/octoberswimmer/aer-dist/sbom/"
+
+// SPDX 2.3 JSON documents. Only the fields aer-dist fills in are modelled.
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	CopyrightText         string            `json:"copyrightText"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	Comment               string            `json:"comment,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+const noAssertion = "NOASSERTION"
+
+// spdx builds the SPDX document for b. As with CycloneDX, every module is
+// related to the application directly. Licenses are not recorded in build
+// information and are left as NOASSERTION.
+func spdx(b Binary) spdxDocument {
+	name := b.Name + "-" + b.Version
+	if b.Platform != "" {
+		name += "-" + b.Platform
+	}
+	app := spdxPackage{
+		Name:                  b.Name,
+		SPDXID:                spdxID("Package", b.Name),
+		VersionInfo:           b.Version,
+		DownloadLocation:      noAssertion,
+		LicenseConcluded:      noAssertion,
+		LicenseDeclared:       noAssertion,
+		CopyrightText:         noAssertion,
+		ExternalRefs:          []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: b.purl()}},
+		PrimaryPackagePurpose: "APPLICATION",
+	}
+	if b.SHA256 != "" {
+		app.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: b.SHA256}}
+	}
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: NamespacePrefix + name + "-" + b.uuid(),
+		CreationInfo: spdxCreationInfo{
+			Created:  b.created().Format(time.RFC3339),
+			Creators: []string{"Tool: aer-dist-release"},
+		},
+		Packages: []spdxPackage{app},
+		Relationships: []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: app.SPDXID},
+		},
+	}
+	for _, m := range b.modules() {
+		pkg := spdxPackage{
+			Name:             m.Path,
+			SPDXID:           spdxID("Package", m.Path, m.Version),
+			VersionInfo:      m.Version,
+			DownloadLocation: noAssertion,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  noAssertion,
+			CopyrightText:    noAssertion,
+			ExternalRefs:     []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: m.purl()}},
+		}
+		if m.Sum != "" {
+			// go.sum's h1 hash covers the module's files, not an archive, so
+			// it is not an SPDX package checksum.
+			pkg.Comment = "go.sum " + m.Sum
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      app.SPDXID,
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+	return doc
+}
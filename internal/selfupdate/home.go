@@ -0,0 +1,212 @@
+// Package selfupdate manages side-by-side aer installs for `aer self-update`
+// and `aer versions`.
+//
+// Installs live under a data directory laid out as:
+//
+//	versions/<version>/aer   one directory per installed release
+//	current                  the default version
+//	bin/aer                  a shim that runs the selected version
+//
+// The shim picks $AER_VERSION, then the nearest .aer-version file (the same
+// pin the GitHub Action reads), then the default, so a project runs the same
+// aer locally as in CI.
+package selfupdate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"aer/internal/release"
+)
+
+// Home is the data directory holding side-by-side installs.
+type Home struct {
+	Root string
+}
+
+// DefaultHome returns $AER_HOME, or the aer directory under the XDG data
+// directory ($XDG_DATA_HOME, defaulting to ~/.local/share, or %LOCALAPPDATA%
+// on Windows).
+func DefaultHome() (Home, error) {
+	if root := os.Getenv("AER_HOME"); root != "" {
+		return Home{Root: root}, nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return Home{Root: filepath.Join(dir, "aer")}, nil
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return Home{Root: filepath.Join(dir, "aer")}, nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Home{}, fmt.Errorf("locate data directory: %w", err)
+	}
+	return Home{Root: filepath.Join(home, ".local", "share", "aer")}, nil
+}
+
+// binaryName is the aer executable's name on this host.
+func binaryName() string {
+	if runtime.GOOS == "windows" {
+		return "aer.exe"
+	}
+	return "aer"
+}
+
+// Binary returns the path of an installed version's executable.
+func (h Home) Binary(version string) string {
+	return filepath.Join(h.Root, "versions", version, binaryName())
+}
+
+// BinDir returns the directory holding the shim, which belongs on PATH.
+func (h Home) BinDir() string {
+	return filepath.Join(h.Root, "bin")
+}
+
+// Installed lists the installed versions, oldest first.
+func (h Home) Installed() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(h.Root, "versions"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(h.Binary(entry.Name())); err == nil {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return release.CompareVersions(versions[i], versions[j]) < 0 })
+	return versions, nil
+}
+
+// Current returns the default version, or "" when none is set.
+func (h Home) Current() (string, error) {
+	data, err := os.ReadFile(filepath.Join(h.Root, "current"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SetCurrent makes an installed version the default.
+func (h Home) SetCurrent(version string) error {
+	if err := checkVersionName(version); err != nil {
+		return err
+	}
+	if _, err := os.Stat(h.Binary(version)); err != nil {
+		return fmt.Errorf("aer %s is not installed", version)
+	}
+	return os.WriteFile(filepath.Join(h.Root, "current"), []byte(version+"\n"), 0o644)
+}
+
+// WriteShim writes the shim that dispatches to the selected version and
+// returns its path.
+func (h Home) WriteShim() (string, error) {
+	if err := os.MkdirAll(h.BinDir(), 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(h.BinDir(), "aer")
+	script := shimScript(h.Root)
+	if runtime.GOOS == "windows" {
+		path += ".cmd"
+		script = shimBatch(h.Root)
+	}
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// checkVersionName rejects versions that cannot be used as a directory
+// name, so a tag cannot escape the versions directory.
+func checkVersionName(version string) error {
+	if version == "" || version == "." || version == ".." || strings.ContainsAny(version, `/\`) {
+		return fmt.Errorf("invalid version %q", version)
+	}
+	return nil
+}
+
+func shimScript(root string) string {
+	return `#!/bin/sh
+# Generated by aer self-update. Runs the aer version named by $AER_VERSION,
+# the nearest .aer-version file, or the default set by aer self-update.
+AER_HOME='` + strings.ReplaceAll(root, `'`, `'\''`) + `'
+version="${AER_VERSION:-}"
+if [ -z "$version" ]; then
+	dir=$PWD
+	while :; do
+		if [ -f "$dir/.aer-version" ]; then
+			version=$(sed -e '/^[[:space:]]*#/d' -e 's/^[[:space:]]*//' -e 's/[[:space:]]*$//' "$dir/.aer-version" | grep -v '^$' | head -n 1)
+			break
+		fi
+		[ "$dir" = / ] && break
+		dir=$(dirname "$dir")
+	done
+fi
+if [ -z "$version" ] || [ "$version" = latest ]; then
+	version=$(cat "$AER_HOME/current" 2>/dev/null)
+fi
+exe="$AER_HOME/versions/$version/aer"
+if [ ! -x "$exe" ]; then
+	echo "aer ${version:-(no default)} is not installed; run: aer self-update --version ${version:-latest}" >&2
+	exit 1
+fi
+exec "$exe" "$@"
+`
+}
+
+// shimBatch is the Windows counterpart of shimScript. for /f takes the first
+// word of the first line not starting with #, as the POSIX shim's sed does.
+func shimBatch(root string) string {
+	lines := []string{
+		"@echo off",
+		"rem Generated by aer self-update. Runs the aer version named by %AER_VERSION%,",
+		"rem the nearest .aer-version file, or the default set by aer self-update.",
+		"setlocal",
+		`set "AER_HOME=` + root + `"`,
+		`set "version=%AER_VERSION%"`,
+		`if not defined version (`,
+		`	set "dir=%CD%"`,
+		`	call :find`,
+		`)`,
+		`if "%version%"=="latest" set "version="`,
+		`if not defined version if exist "%AER_HOME%\current" set /p version=<"%AER_HOME%\current"`,
+		`if not defined version (`,
+		`	echo aer has no default version; run: aer self-update --version latest 1>&2`,
+		`	exit /b 1`,
+		`)`,
+		`set "exe=%AER_HOME%\versions\%version%\aer.exe"`,
+		`if not exist "%exe%" (`,
+		`	echo aer %version% is not installed; run: aer self-update --version %version% 1>&2`,
+		`	exit /b 1`,
+		`)`,
+		`"%exe%" %*`,
+		`exit /b %ERRORLEVEL%`,
+		``,
+		`:find`,
+		`if exist "%dir%\.aer-version" (`,
+		`	for /f "usebackq eol=# tokens=1" %%v in ("%dir%\.aer-version") do if not defined version set "version=%%v"`,
+		`	goto :eof`,
+		`)`,
+		`for %%p in ("%dir%\..") do set "parent=%%~fp"`,
+		`if /i "%parent%"=="%dir%" goto :eof`,
+		`set "dir=%parent%"`,
+		`goto find`,
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
@@ -0,0 +1,230 @@
+package selfupdate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"aer/internal/fakegithub"
+	"aer/internal/github"
+	"aer/internal/installer"
+	"aer/internal/release"
+)
+
+// publish adds a release whose archive for the test host contains an aer
+// stand-in reporting version.
+func publish(t *testing.T, server *fakegithub.Server, version string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aer uses a shell script")
+	}
+	candidates, err := installer.ResolveTargets(runtime.GOOS, runtime.GOARCH, "")
+	if err != nil {
+		t.Skipf("no aer build for the test host: %v", err)
+	}
+	archive := fakegithub.Zip(t, fakegithub.ZipFile{Name: "aer", Body: "#!/bin/sh\necho \"aer version " + version + "\"\n", Mode: 0o755})
+	name := candidates[0].ArchiveName(version)
+	server.AddRelease(DefaultRepo, fakegithub.Release{Tag: version, Assets: map[string][]byte{
+		name:                    archive,
+		"SHA256SUMS-" + version: fakegithub.Checksums(map[string][]byte{name: archive}),
+	}})
+}
+
+func newUpdater(t *testing.T, server *fakegithub.Server) *Updater {
+	return &Updater{Home: Home{Root: t.TempDir()}, GitHub: server.Client()}
+}
+
+func TestInstallSideBySide(t *testing.T) {
+	server := fakegithub.New(t)
+	publish(t, server, "v1.0.0")
+	publish(t, server, "v1.1.0")
+	u := newUpdater(t, server)
+
+	latest, err := u.Install("")
+	if err != nil {
+		t.Fatalf("Install latest: %v", err)
+	}
+	if latest.Version != "v1.1.0" || latest.AlreadyInstalled {
+		t.Fatalf("unexpected result %+v", latest)
+	}
+	if _, err := u.Install("v1.0.0"); err != nil {
+		t.Fatalf("Install v1.0.0: %v", err)
+	}
+
+	installed, err := u.Home.Installed()
+	if err != nil || strings.Join(installed, " ") != "v1.0.0 v1.1.0" {
+		t.Fatalf("unexpected installed versions %v (%v)", installed, err)
+	}
+
+	requests := len(server.Requests())
+	again, err := u.Install("v1.0.0")
+	if err != nil || !again.AlreadyInstalled {
+		t.Fatalf("expected existing install to be reused, got %+v (%v)", again, err)
+	}
+	if len(server.Requests()) != requests {
+		t.Fatalf("reinstall should not download: %v", server.Requests()[requests:])
+	}
+}
+
+func TestInstallRejectsUnverifiedReleases(t *testing.T) {
+	server := fakegithub.New(t)
+	publish(t, server, "v1.0.0")
+	server.AddRelease(DefaultRepo, fakegithub.Release{Tag: "v2.0.0"})
+	u := newUpdater(t, server)
+
+	if _, err := u.Install("v2.0.0"); err == nil || !strings.Contains(err.Error(), "SHA256SUMS-v2.0.0") {
+		t.Fatalf("expected missing checksums error, got %v", err)
+	}
+	if _, err := u.Install("../v1.0.0"); err == nil {
+		t.Fatal("expected a path-like version to be rejected")
+	}
+	if installed, _ := u.Home.Installed(); len(installed) != 0 {
+		t.Fatalf("failed installs must not leave versions behind: %v", installed)
+	}
+}
+
+func TestInstallUsesManifestLockAndSBOM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aer uses a shell script")
+	}
+	candidates, err := installer.ResolveTargets(runtime.GOOS, runtime.GOARCH, "")
+	if err != nil {
+		t.Skipf("no aer build for the test host: %v", err)
+	}
+	server := fakegithub.New(t)
+	archive := fakegithub.Zip(t, fakegithub.ZipFile{Name: "aer", Body: "#!/bin/sh\necho \"aer version v1.2.0\"\n", Mode: 0o755})
+	name := candidates[0].ArchiveName("v1.2.0")
+	sbomName := release.SBOMName(candidates[0].Asset, "v1.2.0", ".cdx.json")
+	assets := map[string][]byte{name: archive, sbomName: []byte(`{"bomFormat": "CycloneDX"}`)}
+	// Only a manifest is published: self-update must not need SHA256SUMS.
+	server.AddRelease(DefaultRepo, fakegithub.Release{Tag: "v1.2.0", Assets: map[string][]byte{
+		name:                           archive,
+		sbomName:                       assets[sbomName],
+		release.ManifestName("v1.2.0"): fakegithub.Manifest(t, "v1.2.0", assets),
+	}})
+	publish(t, server, "v1.3.0")
+
+	lockfile := filepath.Join(t.TempDir(), "aer.lock")
+	lock, err := release.NewLock(DefaultRepo, "v1.2.0", release.Checksums{name: sha256Hex(archive)})
+	if err != nil {
+		t.Fatalf("NewLock: %v", err)
+	}
+	if err := lock.Write(lockfile); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+
+	u := newUpdater(t, server)
+	u.Lockfile = lockfile
+	u.SBOM = "cyclonedx"
+	result, err := u.Install("")
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if result.Version != "v1.2.0" {
+		t.Fatalf("expected the locked version, got %+v", result)
+	}
+	if data, err := os.ReadFile(result.SBOM); err != nil || !strings.Contains(string(data), "CycloneDX") {
+		t.Fatalf("SBOM not installed at %q: %v", result.SBOM, err)
+	}
+	if _, err := u.Install("v1.3.0"); err == nil || !strings.Contains(err.Error(), "pins v1.2.0") {
+		t.Fatalf("expected the lock file to refuse another version, got %v", err)
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestShimSelectsVersion(t *testing.T) {
+	server := fakegithub.New(t)
+	publish(t, server, "v1.0.0")
+	publish(t, server, "v1.1.0")
+	u := newUpdater(t, server)
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		if _, err := u.Install(version); err != nil {
+			t.Fatalf("Install %s: %v", version, err)
+		}
+	}
+	if err := u.Home.SetCurrent("v1.1.0"); err != nil {
+		t.Fatalf("SetCurrent: %v", err)
+	}
+	if err := u.Home.SetCurrent("v9.9.9"); err == nil {
+		t.Fatal("expected SetCurrent to reject a version that is not installed")
+	}
+	shim, err := u.Home.WriteShim()
+	if err != nil {
+		t.Fatalf("WriteShim: %v", err)
+	}
+
+	project := t.TempDir()
+	nested := filepath.Join(project, "force-app", "main")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	runShim := func(dir string, env ...string) string {
+		t.Helper()
+		cmd := exec.Command(shim, "--version")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("shim: %v\n%s", err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	if got := runShim(nested, "AER_VERSION="); got != "aer version v1.1.0" {
+		t.Fatalf("expected the default version, got %q", got)
+	}
+	if err := os.WriteFile(filepath.Join(project, ".aer-version"), []byte("# pinned\nv1.0.0\n"), 0o644); err != nil {
+		t.Fatalf("write .aer-version: %v", err)
+	}
+	if got := runShim(nested, "AER_VERSION="); got != "aer version v1.0.0" {
+		t.Fatalf("expected the pinned version, got %q", got)
+	}
+	if got := runShim(nested, "AER_VERSION=v1.1.0"); got != "aer version v1.1.0" {
+		t.Fatalf("expected AER_VERSION to win, got %q", got)
+	}
+}
+
+func TestListVersions(t *testing.T) {
+	published := []github.Release{{Tag: "v1.2.0-rc.1", Prerelease: true}, {Tag: "v1.1.0"}, {Tag: "v1.0.0"}}
+	rows := ListVersions([]string{"v1.0.0", "v0.9.0"}, "v1.0.0", "1.0.0", published)
+
+	var buf bytes.Buffer
+	if err := WriteVersions(&buf, rows); err != nil {
+		t.Fatalf("WriteVersions: %v", err)
+	}
+	want := "  v1.2.0-rc.1  pre-release, available\n" +
+		"  v1.1.0       available\n" +
+		"* v1.0.0       installed, default, running\n" +
+		"  v0.9.0       installed\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected listing:\n%s", buf.String())
+	}
+}
+
+func TestOutdated(t *testing.T) {
+	cases := []struct {
+		running, latest string
+		want            bool
+	}{
+		{"v1.0.0", "v1.1.0", true},
+		{"v1.1.0", "v1.1.0", false},
+		{"v1.2.0-rc.1", "v1.1.0", false},
+		{"dev", "v1.1.0", false},
+	}
+	for _, tc := range cases {
+		if got := Outdated(tc.running, tc.latest); got != tc.want {
+			t.Fatalf("Outdated(%s, %s): expected %v", tc.running, tc.latest, tc.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package selfupdate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"aer/internal/github"
+	"aer/internal/installer"
+	"aer/internal/release"
+	"aer/internal/sbom"
+)
+
+// DefaultRepo hosts the aer releases.
+const DefaultRepo = "octoberswimmer/aer-dist"
+
+// Updater installs releases into a Home using the same release resolution,
+// platform selection and verification as the GitHub Action.
+type Updater struct {
+	Home   Home
+	GitHub *github.Client
+	Repo   string
+	// OS and Arch select the build; they default to the running host.
+	OS   string
+	Arch string
+	// PublicKey checks the release checksums' signature. It defaults to the
+	// pinned release key.
+	PublicKey release.PublicKey
+	// RequireSignature refuses releases whose checksums are not signed by
+	// PublicKey.
+	RequireSignature bool
+	// Lockfile, when set, names an aer.lock whose version is installed by
+	// default and whose recorded digest the archive must match.
+	Lockfile string
+	// SBOM, when set, also installs the release's SBOM in this format
+	// (cyclonedx or spdx) next to the binary.
+	SBOM string
+	// Out receives progress messages.
+	Out io.Writer
+}
+
+// Result describes an install.
+type Result struct {
+	Version string
+	Path    string
+	// SBOM is the installed SBOM's path, empty when none was requested or
+	// published.
+	SBOM string
+	// AlreadyInstalled is set when the version was present and nothing was
+	// downloaded.
+	AlreadyInstalled bool
+}
+
+// Latest returns the tag of the newest release.
+func (u *Updater) Latest() (string, error) {
+	return u.GitHub.LatestTag(u.repo())
+}
+
+// Available lists published releases, newest first.
+func (u *Updater) Available() ([]github.Release, error) {
+	return u.GitHub.Releases(u.repo())
+}
+
+// Install installs version, or the latest release when version is empty or
+// "latest", unless it is already installed. With a lock file, those install
+// the locked version instead. Unlike the GitHub Action, self-update
+// never installs a release without a manifest or checksums.
+func (u *Updater) Install(version string) (Result, error) {
+	var locked release.Checksums
+	if u.Lockfile != "" {
+		lock, err := release.ReadLock(u.Lockfile)
+		if err != nil {
+			return Result{}, fmt.Errorf("read lockfile: %w", err)
+		}
+		if version == "" || version == "latest" {
+			version = lock.Version
+		} else if lock.Version != version {
+			return Result{}, fmt.Errorf("%s pins %s but %s was requested", u.Lockfile, lock.Version, version)
+		}
+		locked = lock.Checksums()
+	}
+	if version == "" || version == "latest" {
+		latest, err := u.Latest()
+		if err != nil {
+			return Result{}, fmt.Errorf("resolve latest release: %w", err)
+		}
+		version = latest
+	}
+	if err := checkVersionName(version); err != nil {
+		return Result{}, err
+	}
+	sbomExt := ""
+	if u.SBOM != "" {
+		var err error
+		if sbomExt, err = sbom.Extension(u.SBOM); err != nil {
+			return Result{}, err
+		}
+	}
+
+	path := u.Home.Binary(version)
+	if _, err := os.Stat(path); err == nil {
+		return Result{Version: version, Path: path, AlreadyInstalled: true}, nil
+	}
+
+	hostOS, hostArch := u.OS, u.Arch
+	if hostOS == "" {
+		hostOS = runtime.GOOS
+	}
+	if hostArch == "" {
+		hostArch = runtime.GOARCH
+	}
+	libc := ""
+	if hostOS == runtime.GOOS {
+		libc = installer.DetectLibc()
+	}
+	candidates, err := installer.ResolveTargets(hostOS, hostArch, libc)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "aer-self-update-*")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	req := installer.Request{
+		Source:           installer.ReleaseSource{Client: u.GitHub, Repo: u.repo(), Version: version},
+		Version:          version,
+		Candidates:       candidates,
+		TmpDir:           tmpDir,
+		RequireChecksums: true,
+		PublicKey:        u.publicKey(),
+		RequireSignature: u.RequireSignature,
+		Locked:           locked,
+		Lockfile:         u.Lockfile,
+		Log:              textLogger{out: u.Out},
+	}
+	fetched, err := installer.Fetch(req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	extracted, err := installer.ExtractBinary(fetched.Path, fetched.Candidate.Binary, tmpDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("extract binary: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Result{}, err
+	}
+	if err := installer.MoveFile(extracted, path); err != nil {
+		return Result{}, fmt.Errorf("install binary: %w", err)
+	}
+	if err := os.Chmod(path, 0o755); err != nil {
+		return Result{}, err
+	}
+	if _, err := installer.VerifyBinary(path, version); err != nil {
+		os.RemoveAll(filepath.Dir(path))
+		return Result{}, fmt.Errorf("verify installed binary: %w", err)
+	}
+
+	result := Result{Version: version, Path: path}
+	if u.SBOM != "" {
+		result.SBOM, err = installer.FetchSBOM(req, fetched, u.SBOM, filepath.Join(filepath.Dir(path), "aer"+sbomExt))
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	return result, nil
+}
+
+func (u *Updater) repo() string {
+	if u.Repo == "" {
+		return DefaultRepo
+	}
+	return u.Repo
+}
+
+func (u *Updater) publicKey() release.PublicKey {
+	if u.PublicKey != nil {
+		return u.PublicKey
+	}
+	return release.ReleaseKey()
+}
+
+// textLogger writes installer progress as plain lines for the terminal.
+type textLogger struct {
+	out io.Writer
+}
+
+func (l textLogger) Debugf(format string, args ...any) {}
+
+func (l textLogger) Infof(format string, args ...any) {
+	if l.out != nil {
+		fmt.Fprintf(l.out, format+"\n", args...)
+	}
+}
+
+func (l textLogger) Warningf(format string, args ...any) {
+	l.Infof("warning: "+format, args...)
+}
@@ -0,0 +1,105 @@
+package selfupdate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"aer/internal/github"
+	"aer/internal/installer"
+	"aer/internal/release"
+)
+
+// VersionStatus is one row of `aer versions`.
+type VersionStatus struct {
+	Version    string
+	Installed  bool
+	Default    bool
+	Running    bool
+	Published  bool
+	Prerelease bool
+}
+
+// ListVersions merges installed and published versions, newest first.
+func ListVersions(installed []string, current, running string, published []github.Release) []VersionStatus {
+	byVersion := make(map[string]*VersionStatus)
+	row := func(version string) *VersionStatus {
+		if s, ok := byVersion[version]; ok {
+			return s
+		}
+		s := &VersionStatus{Version: version}
+		byVersion[version] = s
+		return s
+	}
+	for _, version := range installed {
+		row(version).Installed = true
+	}
+	for _, rel := range published {
+		s := row(rel.Tag)
+		s.Published = true
+		s.Prerelease = rel.Prerelease
+	}
+	if current != "" {
+		row(current).Default = true
+	}
+	if running != "" {
+		for version, s := range byVersion {
+			if installer.SameVersion(version, running) {
+				s.Running = true
+			}
+		}
+	}
+
+	rows := make([]VersionStatus, 0, len(byVersion))
+	for _, s := range byVersion {
+		rows = append(rows, *s)
+	}
+	sort.Slice(rows, func(i, j int) bool { return release.CompareVersions(rows[i].Version, rows[j].Version) > 0 })
+	return rows
+}
+
+// WriteVersions prints rows as a table, marking the default version with an
+// asterisk.
+func WriteVersions(w io.Writer, rows []VersionStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range rows {
+		marker := " "
+		if s.Default {
+			marker = "*"
+		}
+		var notes []string
+		if s.Installed {
+			notes = append(notes, "installed")
+		}
+		if s.Default {
+			notes = append(notes, "default")
+		}
+		if s.Running {
+			notes = append(notes, "running")
+		}
+		if s.Prerelease {
+			notes = append(notes, "pre-release")
+		}
+		if !s.Installed && s.Published {
+			notes = append(notes, "available")
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\n", marker, s.Version, strings.Join(notes, ", "))
+	}
+	return tw.Flush()
+}
+
+// Outdated reports whether running is older than latest. Development builds
+// and other non-release versions are never reported as outdated.
+func Outdated(running, latest string) bool {
+	current, err := release.ParseVersion(running)
+	if err != nil {
+		return false
+	}
+	newest, err := release.ParseVersion(latest)
+	if err != nil {
+		return false
+	}
+	return current.Compare(newest) < 0
+}
@@ -1,6 +1,14 @@
 package main
 
-import "github.com/octoberswimmer/aer/cmd"
+import (
+	"fmt"
+	"os"
+
+	"github.com/octoberswimmer/aer/cmd"
+	"github.com/spf13/cobra"
+
+	"aer/internal/buildinfo"
+)
 
 // version is overridden at link time via -ldflags.
 var version = "dev"
@@ -8,8 +16,38 @@ var version = "dev"
 func init() {
 	if version != "" {
 		cmd.RootCmd.Version = version
-		cmd.RootCmd.SetVersionTemplate("aer version {{.Version}}\n")
+		// The build information is rendered when --version runs, not
+		// parsed as a template, so the environment is read at that point
+		// and no field can inject template actions.
+		cobra.AddTemplateFunc("buildinfo", func() string { return buildinfo.Read(version).String() })
+		cmd.RootCmd.SetVersionTemplate("{{buildinfo}}")
+	}
+	cmd.RootCmd.AddCommand(newVersionCmd())
+}
+
+func newVersionCmd() *cobra.Command {
+	var asJSON bool
+
+	c := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version and build information",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			info := buildinfo.Read(version)
+			if !asJSON {
+				fmt.Print(info)
+				return nil
+			}
+			data, err := info.JSON()
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		},
 	}
+	c.Flags().BoolVar(&asJSON, "json", false, "print the build information as JSON")
+	return c
 }
 
 func main() {
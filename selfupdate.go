@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/octoberswimmer/aer/cmd"
+	"github.com/spf13/cobra"
+
+	"aer/internal/github"
+	"aer/internal/release"
+	"aer/internal/selfupdate"
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(newSelfUpdateCmd(), newVersionsCmd())
+}
+
+// newUpdater returns an Updater for the default data directory, verifying
+// checksum signatures against the key at publicKey, or the pinned release
+// key when none is given. A key given explicitly must have signed the
+// release.
+func newUpdater(repo, publicKey string) (*selfupdate.Updater, error) {
+	home, err := selfupdate.DefaultHome()
+	if err != nil {
+		return nil, err
+	}
+	u := &selfupdate.Updater{Home: home, GitHub: github.NewClient(), Repo: repo, Out: os.Stderr}
+	if publicKey != "" {
+		data, err := os.ReadFile(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		key, err := release.ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", publicKey, err)
+		}
+		u.PublicKey, u.RequireSignature = key, true
+	}
+	return u, nil
+}
+
+func newSelfUpdateCmd() *cobra.Command {
+	var target string
+	var repo string
+	var publicKey string
+	var lockfile string
+	var sbomFormat string
+	var keepDefault bool
+	var requireSignature bool
+
+	c := &cobra.Command{
+		Use:   "self-update",
+		Short: "Install another aer release side by side and make it the default",
+		Long: `Install an aer release into the aer data directory ($AER_HOME, or aer under
+$XDG_DATA_HOME) and make it the default. Installed versions are kept side by
+side; the shim in the data directory's bin folder runs $AER_VERSION, the
+version pinned in the nearest .aer-version file, or the default.`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			u, err := newUpdater(repo, publicKey)
+			if err != nil {
+				return err
+			}
+			u.Lockfile, u.SBOM = lockfile, sbomFormat
+			u.RequireSignature = u.RequireSignature || requireSignature
+			result, err := u.Install(target)
+			if err != nil {
+				return err
+			}
+			if result.AlreadyInstalled {
+				fmt.Printf("aer %s is already installed at %s\n", result.Version, result.Path)
+			} else {
+				fmt.Printf("Installed aer %s to %s\n", result.Version, result.Path)
+			}
+			if result.SBOM != "" {
+				fmt.Printf("Installed the SBOM to %s\n", result.SBOM)
+			}
+
+			shim, err := u.Home.WriteShim()
+			if err != nil {
+				return fmt.Errorf("write shim: %w", err)
+			}
+			if !keepDefault {
+				if err := u.Home.SetCurrent(result.Version); err != nil {
+					return err
+				}
+				fmt.Printf("aer %s is now the default\n", result.Version)
+			}
+			if !onPath(u.Home.BinDir()) {
+				fmt.Printf("Add %s to your PATH to run the selected version as aer (shim: %s)\n", u.Home.BinDir(), shim)
+			}
+			return nil
+		},
+	}
+	c.Flags().StringVar(&target, "version", "latest", "release to install")
+	c.Flags().StringVar(&repo, "repo", selfupdate.DefaultRepo, "repository that publishes aer releases")
+	c.Flags().StringVar(&publicKey, "public-key", "", "minisign or PEM public key that signs the release checksums (default: the pinned release key)")
+	c.Flags().BoolVar(&requireSignature, "require-signature", false, "refuse to install a release whose checksums are not signed")
+	c.Flags().StringVar(&lockfile, "lockfile", "", "aer.lock whose version to install and whose recorded digest the archive must match")
+	c.Flags().StringVar(&sbomFormat, "sbom", "", "also install the release's SBOM: cyclonedx or spdx")
+	c.Flags().BoolVar(&keepDefault, "no-default", false, "install without changing the default version")
+	c.Flags().MarkHidden("repo")
+	return c
+}
+
+func newVersionsCmd() *cobra.Command {
+	var repo string
+	var installedOnly bool
+
+	c := &cobra.Command{
+		Use:   "versions",
+		Short: "List installed and available aer releases",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			u, err := newUpdater(repo, "")
+			if err != nil {
+				return err
+			}
+			installed, err := u.Home.Installed()
+			if err != nil {
+				return err
+			}
+			current, err := u.Home.Current()
+			if err != nil {
+				return err
+			}
+
+			var published []github.Release
+			if !installedOnly {
+				published, err = u.Available()
+				if err != nil {
+					return fmt.Errorf("list releases: %w", err)
+				}
+			}
+			if err := selfupdate.WriteVersions(os.Stdout, selfupdate.ListVersions(installed, current, version, published)); err != nil {
+				return err
+			}
+
+			if !installedOnly {
+				if latest, err := u.Latest(); err == nil && selfupdate.Outdated(version, latest) {
+					fmt.Printf("\naer %s is running; %s is available. Run `aer self-update` to upgrade.\n", version, latest)
+				}
+			}
+			return nil
+		},
+	}
+	c.Flags().StringVar(&repo, "repo", selfupdate.DefaultRepo, "repository that publishes aer releases")
+	c.Flags().BoolVar(&installedOnly, "installed", false, "only list installed versions, without contacting GitHub")
+	c.Flags().MarkHidden("repo")
+	return c
+}
+
+func onPath(dir string) bool {
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if strings.EqualFold(filepath.Clean(entry), filepath.Clean(dir)) {
+			return true
+		}
+	}
+	return false
+}